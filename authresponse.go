@@ -0,0 +1,178 @@
+package oauth2
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+var (
+	// ErrStateMismatch is returned by ParseAuthorizationResponse when the
+	// 'state' parameter of the response doesn't match the expected state.
+	ErrStateMismatch = errors.New("oauth2: authorization response 'state' parameter does not match")
+
+	// ErrIssuerMismatch is returned by ParseAuthorizationResponse when the
+	// RFC 9207 'iss' parameter of the response doesn't match the
+	// Endpoint.Issuer, or is missing when Endpoint.IssuerParameterSupported.
+	ErrIssuerMismatch = errors.New("oauth2: authorization response 'iss' parameter does not match")
+)
+
+// AuthorizationResponse describes the parameters of a successful authorization
+// response delivered to the RedirectURL.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-4.1.2
+type AuthorizationResponse struct {
+	// Code is the authorization code to pass to Config.Exchange.
+	Code string
+	// State is the 'state' parameter of the authorization request.
+	State string
+	// Issuer is the issuer identifier of the authorization server which
+	// created the response.
+	Issuer string
+
+	// raw contains every parameter of the response.
+	raw map[string]any
+}
+
+// Extra returns a parameter of the response, including any which are not
+// represented by the AuthorizationResponse fields.
+func (r *AuthorizationResponse) Extra(key string) any {
+	return r.raw[key]
+}
+
+// AuthorizationError is the error returned when the authorization server
+// responds to an authorization request with RFC 6749's 'error' parameter.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-4.1.2.1
+type AuthorizationError struct {
+	*BaseError
+
+	// State is the 'state' parameter of the authorization request.
+	State string
+}
+
+// ParseAuthorizationResponse parses the authorization response r received at
+// the RedirectURL. The parameters are read from the POST form body for the
+// form_post response mode, or from a fragment forwarded to the server as a
+// form, and otherwise from the query. JARM responses, which have a 'response'
+// parameter, are parsed with ParseJARM.
+//
+// The 'state' parameter must match state, which must not be empty, or
+// ErrStateMismatch is returned. This protects against CSRF attacks when the
+// state is a value bound to the user agent's session.
+//
+// To protect against mix-up attacks, the RFC 9207 'iss' parameter must match
+// the Endpoint.Issuer when both are present, and it's required when
+// Endpoint.IssuerParameterSupported. Otherwise ErrIssuerMismatch is returned.
+//
+// If the response contains RFC 6749's 'error' parameter, an
+// *AuthorizationError is returned.
+//
+// See https://datatracker.ietf.org/doc/html/rfc9207.
+func (c *Config) ParseAuthorizationResponse(r *http.Request, state string) (*AuthorizationResponse, error) {
+	if state == "" {
+		return nil, errors.New("oauth2: cannot validate authorization response: no state was provided")
+	}
+
+	v, err := authorizationResponseValues(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if response := v.Get("response"); response != "" {
+		ar, err := c.ParseJARM(r.Context(), response)
+
+		var aerr *AuthorizationError
+
+		switch {
+		case errors.As(err, &aerr):
+			if !stateMatches(aerr.State, state) {
+				return nil, ErrStateMismatch
+			}
+
+			return nil, err
+		case err != nil:
+			return nil, err
+		case !stateMatches(ar.State, state):
+			return nil, ErrStateMismatch
+		case ar.Code == "":
+			return nil, errors.New("oauth2: authorization response has no 'code' parameter")
+		}
+
+		return ar, nil
+	}
+
+	if !stateMatches(v.Get("state"), state) {
+		return nil, ErrStateMismatch
+	}
+
+	if err = c.validateIssuerParameter(v); err != nil {
+		return nil, err
+	}
+
+	if code := v.Get("error"); code != "" {
+		return nil, &AuthorizationError{
+			BaseError: &BaseError{
+				Body:             []byte(v.Encode()),
+				ErrorCode:        code,
+				ErrorDescription: v.Get("error_description"),
+				ErrorURI:         v.Get("error_uri"),
+			},
+			State: v.Get("state"),
+		}
+	}
+
+	ar := &AuthorizationResponse{
+		Code:   v.Get("code"),
+		State:  v.Get("state"),
+		Issuer: v.Get("iss"),
+		raw:    make(map[string]any, len(v)),
+	}
+
+	if ar.Code == "" {
+		return nil, errors.New("oauth2: authorization response has no 'code' parameter")
+	}
+
+	for key, values := range v {
+		ar.raw[key] = values[0]
+	}
+
+	return ar, nil
+}
+
+// authorizationResponseValues returns the parameters of the authorization
+// response r.
+func authorizationResponseValues(r *http.Request) (url.Values, error) {
+	if r.Method != http.MethodPost {
+		return r.URL.Query(), nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse authorization response: %w", err)
+	}
+
+	return r.PostForm, nil
+}
+
+// validateIssuerParameter validates the RFC 9207 'iss' parameter of the
+// authorization response parameters v.
+func (c *Config) validateIssuerParameter(v url.Values) error {
+	iss, ok := v["iss"]
+
+	switch {
+	case !ok && c.Endpoint.IssuerParameterSupported:
+		return fmt.Errorf("%w: no 'iss' parameter was provided", ErrIssuerMismatch)
+	case !ok || c.Endpoint.Issuer == "":
+		return nil
+	case len(iss) != 1 || iss[0] != c.Endpoint.Issuer:
+		return fmt.Errorf("%w: got %q, want %q", ErrIssuerMismatch, v.Get("iss"), c.Endpoint.Issuer)
+	}
+
+	return nil
+}
+
+// stateMatches reports whether the state of a response matches the expected
+// state.
+func stateMatches(state, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(state), []byte(expected)) == 1
+}
@@ -0,0 +1,112 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestParseAuthorizationResponse(t *testing.T) {
+	conf := newConf("server")
+	conf.Endpoint.Issuer = "https://as.example.com"
+
+	ar, err := conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?code=CODE&state=STATE&iss=https%3A%2F%2Fas.example.com&session_state=abc", nil), "STATE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.Code != "CODE" || ar.State != "STATE" || ar.Issuer != "https://as.example.com" {
+		t.Errorf("Unexpected authorization response %+v", ar)
+	}
+	if got := ar.Extra("session_state"); got != "abc" {
+		t.Errorf("Extra(session_state) = %v; want abc", got)
+	}
+
+	// form_post, where the query must be ignored.
+	r := httptest.NewRequest("POST", "/callback?code=OTHER", strings.NewReader(url.Values{"code": {"CODE"}, "state": {"STATE"}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ar, err = conf.ParseAuthorizationResponse(r, "STATE"); err != nil || ar.Code != "CODE" {
+		t.Errorf("ParseAuthorizationResponse(form_post) = %+v, %v; want code CODE", ar, err)
+	}
+
+	_, err = conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?error=access_denied&error_description=denied&error_uri=https%3A%2F%2Fas.example.com%2Ferror&state=STATE", nil), "STATE")
+	var aErr *AuthorizationError
+	if !errors.As(err, &aErr) || aErr.ErrorCode != "access_denied" || aErr.ErrorDescription != "denied" || aErr.ErrorURI != "https://as.example.com/error" || aErr.State != "STATE" {
+		t.Errorf("ParseAuthorizationResponse error = %v; want AuthorizationError access_denied", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{"state mismatch", "code=CODE&state=OTHER", ErrStateMismatch},
+		{"no state", "code=CODE", ErrStateMismatch},
+		{"error with state mismatch", "error=access_denied&state=OTHER", ErrStateMismatch},
+		{"issuer mismatch", "code=CODE&state=STATE&iss=https%3A%2F%2Fother.example.com", ErrIssuerMismatch},
+		{"error with issuer mismatch", "error=access_denied&state=STATE&iss=https%3A%2F%2Fother.example.com", ErrIssuerMismatch},
+		{"no code", "state=STATE", nil},
+	}
+	for _, tt := range tests {
+		_, err := conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?"+tt.query, nil), "STATE")
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("%s: ParseAuthorizationResponse error = %v; want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err = conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?code=CODE&state=STATE", nil), ""); err == nil {
+		t.Error("ParseAuthorizationResponse without expected state = nil error; want error")
+	}
+
+	conf.Endpoint.IssuerParameterSupported = true
+	if _, err = conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?code=CODE&state=STATE", nil), "STATE"); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("ParseAuthorizationResponse without required iss error = %v; want ErrIssuerMismatch", err)
+	}
+}
+
+func TestParseAuthorizationResponseJARM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := jws.PublicJWK(key.Public())
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.Issuer = "https://as.example.com"
+	conf.Endpoint.JWKSURL = ts.URL
+
+	signer, alg, _ := jws.NewSigner("", key)
+	response, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg}, map[string]any{
+		"iss":   "https://as.example.com",
+		"aud":   "CLIENT_ID",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"code":  "CODE",
+		"state": "STATE",
+	}, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ar, err := conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?response="+response, nil), "STATE")
+	if err != nil || ar.Code != "CODE" {
+		t.Errorf("ParseAuthorizationResponse(jwt) = %+v, %v; want code CODE", ar, err)
+	}
+
+	if _, err = conf.ParseAuthorizationResponse(httptest.NewRequest("GET", "/callback?response="+response, nil), "OTHER"); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("ParseAuthorizationResponse(jwt) with other state error = %v; want ErrStateMismatch", err)
+	}
+}
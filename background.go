@@ -0,0 +1,226 @@
+package oauth2
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRefreshFraction is the fraction of a token's lifetime after which
+	// a BackgroundTokenSource refreshes it when BackgroundRefresh.Fraction is
+	// zero.
+	DefaultRefreshFraction = 0.8
+
+	// DefaultRefreshJitter is the maximum fraction of a token's lifetime
+	// by which a BackgroundTokenSource randomly refreshes it early when
+	// BackgroundRefresh.Jitter is zero.
+	DefaultRefreshJitter = 0.1
+)
+
+// minRefreshRetry is the minimum delay before a failed background refresh is
+// retried.
+const minRefreshRetry = time.Second
+
+// BackgroundRefresh configures when a BackgroundTokenSource refreshes tokens.
+type BackgroundRefresh struct {
+	// Fraction optionally specifies the fraction of a token's lifetime, between
+	// 0 and 1, after which it's refreshed. If zero, DefaultRefreshFraction is
+	// used.
+	Fraction float64
+
+	// Jitter optionally specifies the maximum fraction of a token's lifetime
+	// by which the refresh is randomly brought forward, so that many clients
+	// which obtained their tokens at the same time don't refresh them at the
+	// same time. If zero, DefaultRefreshJitter is used, and if negative there
+	// is no jitter.
+	Jitter float64
+
+	// OnError is optionally called with the error of each failed background
+	// refresh. The still valid token continues to be returned, and the
+	// refresh is retried until the token expires, after which Token
+	// refreshes it on demand.
+	OnError func(err error)
+}
+
+// BackgroundTokenSource is a TokenSource which refreshes its token
+// asynchronously before it expires, so that callers aren't blocked on a
+// round trip to the token endpoint. Until the new token is obtained, the
+// still valid token is returned.
+//
+// Tokens without an expiry are never refreshed in the background, and expired
+// tokens are refreshed on demand as ReuseTokenSource does.
+//
+// Close must be called to stop the background refresh once the
+// BackgroundTokenSource is no longer used.
+type BackgroundTokenSource struct {
+	new  TokenSource // called to refresh t.
+	opts BackgroundRefresh
+
+	refreshMu sync.Mutex // serializes calls to new
+
+	mu     sync.Mutex // guards t, timer and closed
+	t      *Token
+	timer  *time.Timer
+	closed bool
+}
+
+// NewBackgroundTokenSource returns a BackgroundTokenSource which starts with
+// t and refreshes it with src. The src may be the TokenSource returned by
+// Config.TokenSource, in which case its token is used when t is nil.
+func NewBackgroundTokenSource(t *Token, src TokenSource, opts BackgroundRefresh) *BackgroundTokenSource {
+	// The token must be obtained from src when it's refreshed, which a
+	// reuseTokenSource would only do once the token has expired.
+	if rt, ok := src.(*reuseTokenSource); ok {
+		if t == nil {
+			rt.mu.Lock()
+			t = rt.t
+			rt.mu.Unlock()
+		}
+		src = rt.new
+	}
+
+	s := &BackgroundTokenSource{
+		new:  src,
+		opts: opts,
+	}
+
+	s.mu.Lock()
+	s.set(t)
+	s.mu.Unlock()
+
+	return s
+}
+
+// Token returns the current token if it's still valid, else will refresh it
+// and return the new one.
+func (s *BackgroundTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	t := s.t
+	s.mu.Unlock()
+
+	if t.Valid() {
+		return t, nil
+	}
+
+	return s.refresh(false)
+}
+
+func (s *BackgroundTokenSource) invalidate(t *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.t != nil && s.t.AccessToken == t.AccessToken {
+		s.t = nil
+	}
+}
+
+// Close stops the background refresh. The BackgroundTokenSource continues
+// to refresh expired tokens on demand.
+func (s *BackgroundTokenSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	return nil
+}
+
+// refresh obtains a new token from s.new. Unless force is set, the current
+// token is returned instead if another caller has already refreshed it.
+func (s *BackgroundTokenSource) refresh(force bool) (*Token, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.Lock()
+	current := s.t
+	s.mu.Unlock()
+
+	if !force && current.Valid() {
+		return current, nil
+	}
+
+	t, err := s.new.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.set(t)
+	s.mu.Unlock()
+
+	return t, nil
+}
+
+// background is called by the timer to refresh the token.
+func (s *BackgroundTokenSource) background() {
+	_, err := s.refresh(true)
+	if err == nil {
+		return
+	}
+
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.t.Valid() {
+		// The next call to Token will refresh it on demand.
+		return
+	}
+
+	retry := time.Until(s.t.Expiry) / 2
+	if retry < minRefreshRetry {
+		retry = minRefreshRetry
+	}
+
+	s.schedule(retry)
+}
+
+// set sets the token and schedules its background refresh. s.mu must be held.
+func (s *BackgroundTokenSource) set(t *Token) {
+	s.t = t
+
+	if t == nil || t.Expiry.IsZero() {
+		return
+	}
+
+	fraction := s.opts.Fraction
+	if fraction <= 0 || fraction >= 1 {
+		fraction = DefaultRefreshFraction
+	}
+
+	jitter := s.opts.Jitter
+	if jitter == 0 {
+		jitter = DefaultRefreshJitter
+	} else if jitter < 0 {
+		jitter = 0
+	}
+
+	// The lifetime is measured from now, as the time the token was issued
+	// isn't known.
+	lifetime := float64(time.Until(t.Expiry))
+	delay := lifetime*fraction - lifetime*jitter*rand.Float64()
+
+	s.schedule(time.Duration(delay))
+}
+
+// schedule schedules a background refresh after delay. s.mu must be held.
+func (s *BackgroundTokenSource) schedule(delay time.Duration) {
+	if s.closed {
+		return
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	s.timer = time.AfterFunc(delay, s.background)
+}
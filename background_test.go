@@ -0,0 +1,105 @@
+package oauth2
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingTokenSource struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (s *countingTokenSource) Token() (*Token, error) {
+	n := s.calls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &Token{AccessToken: string(rune('A' + n)), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestBackgroundTokenSource(t *testing.T) {
+	src := &countingTokenSource{}
+	initial := &Token{AccessToken: "INITIAL", Expiry: time.Now().Add(20 * time.Second)}
+
+	// The refresh is due after 1s of the 20s lifetime.
+	s := NewBackgroundTokenSource(initial, src, BackgroundRefresh{Fraction: 0.05, Jitter: -1})
+	defer s.Close()
+
+	tok, err := s.Token()
+	if err != nil || tok.AccessToken != "INITIAL" || src.calls.Load() != 0 {
+		t.Fatalf("Token = %v, %v after %d refreshes; want INITIAL without refresh", tok, err, src.calls.Load())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for src.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if tok, err = s.Token(); err != nil || tok.AccessToken != "B" || src.calls.Load() != 1 {
+		t.Errorf("Token = %v, %v after %d refreshes; want B after 1 background refresh", tok, err, src.calls.Load())
+	}
+}
+
+func TestBackgroundTokenSourceOnDemand(t *testing.T) {
+	src := &countingTokenSource{}
+
+	s := NewBackgroundTokenSource(nil, src, BackgroundRefresh{})
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Token(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := src.calls.Load(); n != 1 {
+		t.Errorf("Concurrent Token calls refreshed %d times; want 1", n)
+	}
+}
+
+func TestBackgroundTokenSourceError(t *testing.T) {
+	src := &countingTokenSource{err: errors.New("unavailable")}
+	initial := &Token{AccessToken: "INITIAL", Expiry: time.Now().Add(20 * time.Second)}
+
+	errs := make(chan error, 10)
+	s := NewBackgroundTokenSource(initial, src, BackgroundRefresh{Fraction: 0.01, Jitter: -1, OnError: func(err error) {
+		errs <- err
+	}})
+	defer s.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, src.err) {
+			t.Errorf("OnError error = %v; want %v", err, src.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError wasn't called")
+	}
+
+	if tok, err := s.Token(); err != nil || tok.AccessToken != "INITIAL" {
+		t.Errorf("Token after failed refresh = %v, %v; want INITIAL", tok, err)
+	}
+}
+
+func TestBackgroundTokenSourceClose(t *testing.T) {
+	src := &countingTokenSource{}
+	initial := &Token{AccessToken: "INITIAL", Expiry: time.Now().Add(20 * time.Second)}
+
+	s := NewBackgroundTokenSource(initial, src, BackgroundRefresh{Fraction: 0.01, Jitter: -1})
+	s.Close()
+
+	time.Sleep(500 * time.Millisecond)
+	if n := src.calls.Load(); n != 0 {
+		t.Errorf("Closed BackgroundTokenSource refreshed %d times; want 0", n)
+	}
+}
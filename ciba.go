@@ -0,0 +1,257 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+)
+
+// GrantTypeCIBA is the OpenID Connect Client Initiated Backchannel
+// Authentication grant type.
+const GrantTypeCIBA = "urn:openid:params:grant-type:ciba"
+
+// A Hint identifies the end-user for whom authentication is requested with
+// Config.BackchannelAuth. It may also be passed to AuthCodeURL as an
+// AuthCodeOption.
+type Hint struct {
+	key, value string
+}
+
+func (h Hint) setValue(m url.Values) {
+	if h.key != "" {
+		m.Set(h.key, h.value)
+	}
+}
+
+// LoginHint returns a Hint which identifies the end-user with the
+// 'login_hint' parameter, such as an email address or phone number.
+func LoginHint(hint string) Hint {
+	return Hint{"login_hint", hint}
+}
+
+// IDTokenHint returns a Hint which identifies the end-user with the
+// 'id_token_hint' parameter, an ID Token previously issued to the client.
+func IDTokenHint(idToken string) Hint {
+	return Hint{"id_token_hint", idToken}
+}
+
+// LoginHintToken returns a Hint which identifies the end-user with the
+// 'login_hint_token' parameter, a token containing information about them.
+func LoginHintToken(token string) Hint {
+	return Hint{"login_hint_token", token}
+}
+
+// ClientNotificationTokenOption returns the AuthCodeOption which sets the
+// 'client_notification_token' parameter of Config.BackchannelAuth. It's
+// required when the client is registered for the ping token delivery mode, and
+// is the bearer token the authorization server presents to the client
+// notification endpoint. See ParseBackchannelNotification.
+func ClientNotificationTokenOption(token string) AuthCodeOption {
+	return setParam{"client_notification_token", token}
+}
+
+// BackchannelAuthResponse describes a successful OpenID Connect Client
+// Initiated Backchannel Authentication Response.
+// https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.7.3
+type BackchannelAuthResponse struct {
+	// AuthReqID is the identifier of the authentication request.
+	AuthReqID string `json:"auth_req_id"`
+	// Expiry is when the authentication request expires.
+	Expiry time.Time `json:"expires_in,omitempty"`
+	// Interval is the duration in seconds that the client should wait between
+	// polling requests.
+	Interval int64 `json:"interval,omitempty"`
+}
+
+func (b BackchannelAuthResponse) MarshalJSON() ([]byte, error) {
+	type Alias BackchannelAuthResponse
+	var expiresIn int64
+	if !b.Expiry.IsZero() {
+		expiresIn = int64(time.Until(b.Expiry).Seconds())
+	}
+	return json.Marshal(&struct {
+		ExpiresIn int64 `json:"expires_in,omitempty"`
+		*Alias
+	}{
+		ExpiresIn: expiresIn,
+		Alias:     (*Alias)(&b),
+	})
+}
+
+func (b *BackchannelAuthResponse) UnmarshalJSON(data []byte) (err error) {
+	type Alias BackchannelAuthResponse
+
+	aux := &struct {
+		ExpiresIn int64 `json:"expires_in"`
+		*Alias
+	}{
+		Alias: (*Alias)(b),
+	}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.ExpiresIn != 0 {
+		b.Expiry = time.Now().UTC().Add(time.Second * time.Duration(aux.ExpiresIn))
+	}
+
+	return nil
+}
+
+// BackchannelAuth starts an OpenID Connect Client Initiated Backchannel
+// Authentication (CIBA) of the end-user identified by hint at the
+// Endpoint.BackchannelAuthURL. The bindingMessage is optionally displayed on
+// both the consumption and authentication devices. The client authenticates in
+// the same manner as it does to the token endpoint, and the Scopes should
+// include "openid".
+//
+// In the poll mode the token is obtained with BackchannelAccessToken. In the
+// ping mode the ClientNotificationTokenOption must be provided, and the token
+// is obtained with BackchannelToken once the authorization server has called
+// the client notification endpoint.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+//
+// See https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html.
+func (c *Config) BackchannelAuth(ctx context.Context, hint Hint, bindingMessage string, opts ...AuthCodeOption) (*BackchannelAuthResponse, error) {
+	if c.backchannelAuthURL() == "" {
+		return nil, errors.New("endpoint missing BackchannelAuthURL")
+	}
+
+	if hint.key == "" || hint.value == "" {
+		return nil, errors.New("oauth2: backchannel authentication requires a hint")
+	}
+
+	v := url.Values{}
+
+	hint.setValue(v)
+
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	if bindingMessage != "" {
+		v.Set("binding_message", bindingMessage)
+	}
+
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+
+	t := time.Now()
+
+	body, err := internal.RetrieveBackchannelAuthResponse(ctx, c.clientAuth(), c.backchannelAuthURL(), v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get())
+	if err != nil {
+		var rErr *internal.RetrieveError
+
+		if errors.As(err, &rErr) {
+			return nil, &RetrieveError{BaseError: (*BaseError)(rErr)}
+		}
+
+		return nil, err
+	}
+
+	ba := &BackchannelAuthResponse{}
+
+	if err = json.Unmarshal(body, ba); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse backchannel authentication response: %w", err)
+	}
+
+	if ba.AuthReqID == "" {
+		return nil, errors.New("oauth2: backchannel authentication response has no 'auth_req_id'")
+	}
+
+	if !ba.Expiry.IsZero() {
+		// Make a small adjustment to account for time taken by the request
+		ba.Expiry = ba.Expiry.Add(-time.Since(t))
+	}
+
+	return ba, nil
+}
+
+// BackchannelAccessToken polls the token endpoint to exchange the
+// authentication request ID of a poll mode backchannel authentication for a
+// token, until the end-user has authenticated, the authentication request
+// expires, or ctx is done.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func (c *Config) BackchannelAccessToken(ctx context.Context, ba *BackchannelAuthResponse, opts ...AuthCodeOption) (*Token, error) {
+	if !ba.Expiry.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, ba.Expiry)
+		defer cancel()
+	}
+
+	return pollToken(ctx, c, backchannelTokenValues(ba.AuthReqID, opts), ba.Interval)
+}
+
+// BackchannelToken exchanges the authentication request ID of a backchannel
+// authentication for a token with a single token request, such as when the
+// authorization server has notified the client in the ping mode.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func (c *Config) BackchannelToken(ctx context.Context, authReqID string, opts ...AuthCodeOption) (*Token, error) {
+	return retrieveToken(ctx, c, backchannelTokenValues(authReqID, opts))
+}
+
+func backchannelTokenValues(authReqID string, opts []AuthCodeOption) url.Values {
+	// https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.10.1
+	v := url.Values{
+		"grant_type":  {GrantTypeCIBA},
+		"auth_req_id": {authReqID},
+	}
+
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+
+	return v
+}
+
+// ParseBackchannelNotification parses the ping mode notification r the
+// authorization server sends to the client notification endpoint, and returns
+// the authentication request ID which is ready to be exchanged with
+// BackchannelToken. The bearer token of r must match the clientNotificationToken
+// sent with the authentication request.
+//
+// See https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.10.2.
+func ParseBackchannelNotification(r *http.Request, clientNotificationToken string) (authReqID string, err error) {
+	if r.Method != http.MethodPost {
+		return "", fmt.Errorf("oauth2: backchannel notification has unexpected method %s", r.Method)
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || clientNotificationToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(clientNotificationToken)) != 1 {
+		return "", errors.New("oauth2: backchannel notification has an invalid client notification token")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("oauth2: cannot read backchannel notification: %w", err)
+	}
+
+	var notification struct {
+		AuthReqID string `json:"auth_req_id"`
+	}
+
+	if err = json.Unmarshal(body, &notification); err != nil {
+		return "", fmt.Errorf("oauth2: cannot parse backchannel notification: %w", err)
+	}
+
+	if notification.AuthReqID == "" {
+		return "", errors.New("oauth2: backchannel notification has no 'auth_req_id'")
+	}
+
+	return notification.AuthReqID, nil
+}
@@ -0,0 +1,122 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackchannelAuth(t *testing.T) {
+	var polls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/bc-authorize":
+			if user, _, _ := r.BasicAuth(); user != "CLIENT_ID" {
+				t.Errorf("Unexpected client %q", user)
+			}
+			if got := r.PostForm.Get("login_hint"); got != "user@example.com" {
+				t.Errorf("login_hint = %q; want user@example.com", got)
+			}
+			if got := r.PostForm.Get("binding_message"); got != "W4SCT" {
+				t.Errorf("binding_message = %q; want W4SCT", got)
+			}
+			if got := r.PostForm.Get("scope"); got != "scope1 scope2" {
+				t.Errorf("scope = %q; want scope1 scope2", got)
+			}
+			w.Write([]byte(`{"auth_req_id":"AUTH_REQ_ID","expires_in":120,"interval":1}`))
+		case "/token":
+			if got := r.PostForm.Get("grant_type"); got != GrantTypeCIBA {
+				t.Errorf("grant_type = %q; want %q", got, GrantTypeCIBA)
+			}
+			if got := r.PostForm.Get("auth_req_id"); got != "AUTH_REQ_ID" {
+				t.Errorf("auth_req_id = %q; want AUTH_REQ_ID", got)
+			}
+			if polls++; polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"ACCESS_TOKEN","token_type":"bearer","expires_in":3600}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInHeader
+	conf.Endpoint.BackchannelAuthURL = ts.URL + "/bc-authorize"
+
+	ba, err := conf.BackchannelAuth(context.Background(), LoginHint("user@example.com"), "W4SCT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ba.AuthReqID != "AUTH_REQ_ID" || ba.Interval != 1 || time.Until(ba.Expiry) <= 0 {
+		t.Errorf("Unexpected backchannel authentication response %+v", ba)
+	}
+
+	tok, err := conf.BackchannelAccessToken(context.Background(), ba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "ACCESS_TOKEN" || polls != 2 {
+		t.Errorf("BackchannelAccessToken = %q after %d polls; want ACCESS_TOKEN after 2 polls", tok.AccessToken, polls)
+	}
+
+	if _, err = conf.BackchannelAuth(context.Background(), Hint{}, ""); err == nil {
+		t.Error("BackchannelAuth without hint = nil error; want error")
+	}
+}
+
+func TestBackchannelAuthError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"unknown_user_id","error_description":"unknown user"}`))
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Endpoint.BackchannelAuthURL = ts.URL
+
+	_, err := conf.BackchannelAuth(context.Background(), IDTokenHint("ID_TOKEN"), "")
+	var rErr *RetrieveError
+	if !errors.As(err, &rErr) || rErr.ErrorCode != "unknown_user_id" || rErr.ErrorDescription != "unknown user" {
+		t.Errorf("BackchannelAuth error = %v; want RetrieveError unknown_user_id", err)
+	}
+}
+
+func TestParseBackchannelNotification(t *testing.T) {
+	newRequest := func(authorization, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/cb", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", authorization)
+		return r
+	}
+
+	id, err := ParseBackchannelNotification(newRequest("Bearer TOKEN", `{"auth_req_id":"AUTH_REQ_ID"}`), "TOKEN")
+	if err != nil || id != "AUTH_REQ_ID" {
+		t.Errorf("ParseBackchannelNotification = %q, %v; want AUTH_REQ_ID", id, err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+	}{
+		{"wrong token", "Bearer OTHER", `{"auth_req_id":"AUTH_REQ_ID"}`},
+		{"no token", "", `{"auth_req_id":"AUTH_REQ_ID"}`},
+		{"no auth_req_id", "Bearer TOKEN", `{}`},
+		{"invalid body", "Bearer TOKEN", `auth_req_id`},
+	}
+	for _, tt := range tests {
+		if _, err := ParseBackchannelNotification(newRequest(tt.authorization, tt.body), "TOKEN"); err == nil {
+			t.Errorf("ParseBackchannelNotification with %s = nil error; want error", tt.name)
+		}
+	}
+}
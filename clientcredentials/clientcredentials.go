@@ -15,6 +15,8 @@ package clientcredentials // import "authelia.com/client/oauth2/clientcredential
 
 import (
 	"context"
+	"crypto"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -33,10 +35,46 @@ type Config struct {
 	// ClientSecret is the application's secret.
 	ClientSecret string
 
+	// PrivateKey is the application's private key. It is used to sign the
+	// client assertion when AuthStyle is oauth2.AuthStylePrivateKeyJWT, and
+	// may be the same key as the oauth2.Config's PrivateKey.
+	PrivateKey crypto.Signer
+
+	// PrivateKeyID optionally specifies the 'kid' header of the client
+	// assertion signed with PrivateKey.
+	PrivateKeyID string
+
+	// ClientAssertionAlgorithm optionally specifies the JWS algorithm used to
+	// sign client assertions. If empty, it's derived from the PrivateKey, or
+	// HS256 is used for oauth2.AuthStyleClientSecretJWT. The ClientSecret must
+	// then be at least as long as the output of the algorithm's hash, such as
+	// 32 bytes for HS256.
+	ClientAssertionAlgorithm string
+
+	// ClientCertificate is the application's optional TLS client certificate.
+	// When set it's presented to the token endpoint, which then prefers the
+	// MTLSEndpointAliases, and to resource servers through the Client, as
+	// described in RFC 8705. It's required when AuthStyle is
+	// oauth2.AuthStyleTLSClientAuth or oauth2.AuthStyleSelfSignedTLSClientAuth.
+	// The HTTP client's transport must present it itself unless it's an
+	// *http.Transport.
+	ClientCertificate *tls.Certificate
+
+	// DPoP optionally sender-constrains tokens to the client's key as
+	// described in RFC 9449. When set, proofs are sent with token requests
+	// and the Client presents DPoP bound tokens with a fresh proof for each
+	// request.
+	DPoP *oauth2.DPoP
+
 	// TokenURL is the resource server's token endpoint
 	// URL. This is a constant specific to each server.
 	TokenURL string
 
+	// MTLSEndpointAliases optionally specifies the endpoint URLs to use
+	// instead of TokenURL when the Config has a ClientCertificate. Only its
+	// TokenURL is used.
+	MTLSEndpointAliases oauth2.MTLSEndpointAliases
+
 	// Scopes specifies optional requested permissions.
 	Scopes []string
 
@@ -68,7 +106,21 @@ func (c *Config) Token(ctx context.Context) (*oauth2.Token, error) {
 //
 // The returned Client and its Transport should not be modified.
 func (c *Config) Client(ctx context.Context) *http.Client {
-	return oauth2.NewClient(ctx, c.TokenSource(ctx))
+	cctx := internal.ContextWithCertificate(ctx, c.ClientCertificate)
+	if c.DPoP == nil {
+		return oauth2.NewClient(cctx, c.TokenSource(ctx))
+	}
+	cc := internal.ContextClient(cctx)
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Base:   cc.Transport,
+			Source: c.TokenSource(ctx),
+			DPoP:   c.DPoP,
+		},
+		CheckRedirect: cc.CheckRedirect,
+		Jar:           cc.Jar,
+		Timeout:       cc.Timeout,
+	}
 }
 
 // TokenSource returns a TokenSource that returns t until t expires,
@@ -84,6 +136,16 @@ func (c *Config) TokenSource(ctx context.Context) oauth2.TokenSource {
 	return oauth2.ReuseTokenSource(nil, source)
 }
 
+// tokenURL returns the mutual-TLS alias of the TokenURL if the Config uses a
+// client certificate and the alias is configured, otherwise the TokenURL.
+func (c *Config) tokenURL() string {
+	if c.ClientCertificate != nil && c.MTLSEndpointAliases.TokenURL != "" {
+		return c.MTLSEndpointAliases.TokenURL
+	}
+
+	return c.TokenURL
+}
+
 type tokenSource struct {
 	ctx  context.Context
 	conf *Config
@@ -107,7 +169,20 @@ func (c *tokenSource) Token() (*oauth2.Token, error) {
 		v[k] = p
 	}
 
-	tk, err := internal.RetrieveToken(c.ctx, c.conf.ClientID, c.conf.ClientSecret, c.conf.TokenURL, v, internal.AuthStyle(c.conf.AuthStyle), c.conf.authStyleCache.Get())
+	auth := &internal.ClientAuth{
+		ClientID:     c.conf.ClientID,
+		ClientSecret: c.conf.ClientSecret,
+		PrivateKey:   c.conf.PrivateKey,
+		PrivateKeyID: c.conf.PrivateKeyID,
+		Algorithm:    c.conf.ClientAssertionAlgorithm,
+		Audience:     c.conf.TokenURL,
+		Certificate:  c.conf.ClientCertificate,
+	}
+	if c.conf.DPoP != nil {
+		auth.DPoP = c.conf.DPoP
+	}
+
+	tk, err := internal.RetrieveToken(c.ctx, auth, c.conf.tokenURL(), v, internal.AuthStyle(c.conf.AuthStyle), c.conf.authStyleCache.Get())
 	if err != nil {
 		if rErr, ok := err.(*internal.RetrieveError); ok {
 			return nil, &oauth2.RetrieveError{BaseError: (*oauth2.BaseError)(rErr)}
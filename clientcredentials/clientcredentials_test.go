@@ -6,11 +6,14 @@ package clientcredentials
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+
+	"authelia.com/client/oauth2"
 )
 
 func newConf(serverURL string) *Config {
@@ -135,3 +138,31 @@ func TestTokenRefreshRequest(t *testing.T) {
 	c := conf.Client(context.Background())
 	c.Get(ts.URL + "/somethingelse")
 }
+
+func TestTokenRequestMTLSEndpointAlias(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mtls/token" {
+			t.Errorf("token request URL = %q; want %q", r.URL, "/mtls/token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"ACCESS_TOKEN","token_type":"bearer"}`))
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.AuthStyle = oauth2.AuthStyleInHeader
+	conf.MTLSEndpointAliases.TokenURL = ts.URL + "/mtls/token"
+
+	// The alias is only used with a client certificate.
+	conf.ClientCertificate = &tls.Certificate{}
+	if _, err := conf.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.ClientCertificate = nil
+	conf.MTLSEndpointAliases.TokenURL = ts.URL + "/token"
+	conf.TokenURL = ts.URL + "/mtls/token"
+	if _, err := conf.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
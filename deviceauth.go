@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
@@ -102,11 +103,28 @@ func (c *Config) DeviceAuth(ctx context.Context, opts ...AuthCodeOption) (*Devic
 }
 
 func retrieveDeviceAuth(ctx context.Context, c *Config, v url.Values) (*DeviceAuthResponse, error) {
-	if c.Endpoint.DeviceAuthURL == "" {
+	if c.deviceAuthURL() == "" {
 		return nil, errors.New("endpoint missing DeviceAuthURL")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.Endpoint.DeviceAuthURL, strings.NewReader(v.Encode()))
+	ctx = internal.ContextWithCertificate(ctx, c.ClientCertificate)
+
+	var da *DeviceAuthResponse
+
+	ctx = c.hooksContext(ctx)
+
+	err := internal.Retry(ctx, (*internal.RetryPolicy)(c.Retry), true, func() (err error) {
+		da, err = internal.Observe(ctx, EndpointKindDeviceAuth, "", func(ctx context.Context) (*DeviceAuthResponse, error) {
+			return doDeviceAuthRoundTrip(ctx, c.deviceAuthURL(), v)
+		})
+		return err
+	})
+
+	return da, err
+}
+
+func doDeviceAuthRoundTrip(ctx context.Context, deviceAuthURL string, v url.Values) (*DeviceAuthResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, deviceAuthURL, strings.NewReader(v.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -115,10 +133,11 @@ func retrieveDeviceAuth(ctx context.Context, c *Config, v url.Values) (*DeviceAu
 	req.Header.Set("Accept", "application/json")
 
 	t := time.Now()
-	r, err := internal.ContextClient(ctx).Do(req)
+	r, err := internal.ContextClient(ctx).Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
+	defer r.Body.Close()
 
 	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
 	if err != nil {
@@ -126,12 +145,37 @@ func retrieveDeviceAuth(ctx context.Context, c *Config, v url.Values) (*DeviceAu
 	}
 
 	if code := r.StatusCode; code < 200 || code > 299 {
-		return nil, &RetrieveError{
+		rErr := &RetrieveError{
 			&BaseError{
 				Response: r,
 				Body:     body,
 			},
 		}
+
+		// Populate the RFC 6749 error parameters, which is how transient
+		// errors such as 'temporarily_unavailable' are classified.
+		content, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch content {
+		case "application/x-www-form-urlencoded", "text/plain":
+			if vals, err := url.ParseQuery(string(body)); err == nil {
+				rErr.ErrorCode = vals.Get("error")
+				rErr.ErrorDescription = vals.Get("error_description")
+				rErr.ErrorURI = vals.Get("error_uri")
+			}
+		default:
+			var ej struct {
+				ErrorCode        string `json:"error"`
+				ErrorDescription string `json:"error_description"`
+				ErrorURI         string `json:"error_uri"`
+			}
+			if err = json.Unmarshal(body, &ej); err == nil {
+				rErr.ErrorCode = ej.ErrorCode
+				rErr.ErrorDescription = ej.ErrorDescription
+				rErr.ErrorURI = ej.ErrorURI
+			}
+		}
+
+		return nil, rErr
 	}
 
 	da := &DeviceAuthResponse{}
@@ -169,9 +213,16 @@ func (c *Config) DeviceAccessToken(ctx context.Context, da *DeviceAuthResponse,
 		opt.setValue(v)
 	}
 
+	return pollToken(ctx, c, v, da.Interval)
+}
+
+// pollToken polls the token endpoint with the parameters v every interval
+// seconds until a token is issued, the authorization fails, or ctx is done.
+// It's shared by the device authorization and CIBA poll mode grants, which
+// use the same error codes.
+func pollToken(ctx context.Context, c *Config, v url.Values, interval int64) (*Token, error) {
 	// "If no value is provided, clients MUST use 5 as the default."
 	// https://datatracker.ietf.org/doc/html/rfc8628#section-3.2
-	interval := da.Interval
 	if interval == 0 {
 		interval = 5
 	}
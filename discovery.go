@@ -0,0 +1,272 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"authelia.com/client/oauth2/internal"
+)
+
+// ProviderMetadata describes the OpenID Connect 1.0 Discovery and RFC 8414
+// Authorization Server Metadata document of an authorization server.
+//
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+// and https://datatracker.ietf.org/doc/html/rfc8414#section-2.
+type ProviderMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                              string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint,omitempty"`
+	JWKSURI                                    string   `json:"jwks_uri,omitempty"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint,omitempty"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint,omitempty"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint,omitempty"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint,omitempty"`
+	BackchannelAuthenticationEndpoint          string   `json:"backchannel_authentication_endpoint,omitempty"`
+	ScopesSupported                            []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported                     []string `json:"response_types_supported,omitempty"`
+	ResponseModesSupported                     []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported                        []string `json:"grant_types_supported,omitempty"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported,omitempty"`
+	ClaimsSupported                            []string `json:"claims_supported,omitempty"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	IDTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported,omitempty"`
+	IDTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported,omitempty"`
+	UserinfoSigningAlgValuesSupported          []string `json:"userinfo_signing_alg_values_supported,omitempty"`
+	UserinfoEncryptionAlgValuesSupported       []string `json:"userinfo_encryption_alg_values_supported,omitempty"`
+	UserinfoEncryptionEncValuesSupported       []string `json:"userinfo_encryption_enc_values_supported,omitempty"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported,omitempty"`
+	RequestObjectEncryptionAlgValuesSupported  []string `json:"request_object_encryption_alg_values_supported,omitempty"`
+	RequestObjectEncryptionEncValuesSupported  []string `json:"request_object_encryption_enc_values_supported,omitempty"`
+	AuthorizationSigningAlgValuesSupported     []string `json:"authorization_signing_alg_values_supported,omitempty"`
+	AuthorizationEncryptionAlgValuesSupported  []string `json:"authorization_encryption_alg_values_supported,omitempty"`
+	AuthorizationEncryptionEncValuesSupported  []string `json:"authorization_encryption_enc_values_supported,omitempty"`
+	DPoPSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported,omitempty"`
+	ClaimsParameterSupported                   bool     `json:"claims_parameter_supported,omitempty"`
+	RequestParameterSupported                  bool     `json:"request_parameter_supported,omitempty"`
+	RequestURIParameterSupported               bool     `json:"request_uri_parameter_supported,omitempty"`
+	RequireRequestURIRegistration              bool     `json:"require_request_uri_registration,omitempty"`
+	RequirePushedAuthorizationRequests         bool     `json:"require_pushed_authorization_requests,omitempty"`
+	RequireSignedRequestObject                 bool     `json:"require_signed_request_object,omitempty"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	AuthorizationResponseIssParameterSupported bool     `json:"authorization_response_iss_parameter_supported,omitempty"`
+	ServiceDocumentation                       string   `json:"service_documentation,omitempty"`
+	OPPolicyURI                                string   `json:"op_policy_uri,omitempty"`
+	OPTosURI                                   string   `json:"op_tos_uri,omitempty"`
+
+	// The OpenID Connect Client Initiated Backchannel Authentication metadata.
+	BackchannelTokenDeliveryModesSupported                    []string `json:"backchannel_token_delivery_modes_supported,omitempty"`
+	BackchannelAuthenticationRequestSigningAlgValuesSupported []string `json:"backchannel_authentication_request_signing_alg_values_supported,omitempty"`
+	BackchannelUserCodeParameterSupported                     bool     `json:"backchannel_user_code_parameter_supported,omitempty"`
+
+	// MTLSEndpointAliases are the RFC 8705 'mtls_endpoint_aliases'.
+	MTLSEndpointAliases MTLSEndpointAliases `json:"-"`
+
+	// raw contains every member of the document.
+	raw map[string]any
+}
+
+func (m *ProviderMetadata) UnmarshalJSON(data []byte) (err error) {
+	type Alias ProviderMetadata
+
+	aux := &struct {
+		MTLSEndpointAliases *struct {
+			DeviceAuthorizationEndpoint        string `json:"device_authorization_endpoint"`
+			PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+			BackchannelAuthenticationEndpoint  string `json:"backchannel_authentication_endpoint"`
+			TokenEndpoint                      string `json:"token_endpoint"`
+			IntrospectionEndpoint              string `json:"introspection_endpoint"`
+			RevocationEndpoint                 string `json:"revocation_endpoint"`
+			UserinfoEndpoint                   string `json:"userinfo_endpoint"`
+		} `json:"mtls_endpoint_aliases"`
+		*Alias
+	}{
+		Alias: (*Alias)(m),
+	}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aliases := aux.MTLSEndpointAliases; aliases != nil {
+		m.MTLSEndpointAliases = MTLSEndpointAliases{
+			DeviceAuthURL:      aliases.DeviceAuthorizationEndpoint,
+			PushedAuthURL:      aliases.PushedAuthorizationRequestEndpoint,
+			BackchannelAuthURL: aliases.BackchannelAuthenticationEndpoint,
+			TokenURL:           aliases.TokenEndpoint,
+			IntrospectionURL:   aliases.IntrospectionEndpoint,
+			RevocationURL:      aliases.RevocationEndpoint,
+			UserinfoURL:        aliases.UserinfoEndpoint,
+		}
+	}
+
+	return json.Unmarshal(data, &m.raw)
+}
+
+// Extra returns a member of the metadata document, including any which are
+// not represented by the ProviderMetadata fields.
+func (m *ProviderMetadata) Extra(key string) any {
+	return m.raw[key]
+}
+
+// Endpoint returns the Endpoint described by the metadata.
+//
+// The AuthStyle is chosen from the 'token_endpoint_auth_methods_supported',
+// preferring client_secret_basic, then client_secret_post, then the first of
+// the other supported methods. Clients which authenticate with a method other
+// than a client secret should set the AuthStyle explicitly.
+func (m *ProviderMetadata) Endpoint() Endpoint {
+	return Endpoint{
+		Issuer:              m.Issuer,
+		AuthURL:             m.AuthorizationEndpoint,
+		DeviceAuthURL:       m.DeviceAuthorizationEndpoint,
+		PushedAuthURL:       m.PushedAuthorizationRequestEndpoint,
+		BackchannelAuthURL:  m.BackchannelAuthenticationEndpoint,
+		TokenURL:            m.TokenEndpoint,
+		IntrospectionURL:    m.IntrospectionEndpoint,
+		RevocationURL:       m.RevocationEndpoint,
+		UserinfoURL:         m.UserinfoEndpoint,
+		JWKSURL:             m.JWKSURI,
+		MTLSEndpointAliases: m.MTLSEndpointAliases,
+		AuthStyle:           authStyleFromMethods(m.TokenEndpointAuthMethodsSupported),
+
+		IssuerParameterSupported: m.AuthorizationResponseIssParameterSupported,
+	}
+}
+
+func authStyleFromMethods(methods []string) AuthStyle {
+	// The default when omitted is client_secret_basic per RFC 8414 section 2.
+	if len(methods) == 0 {
+		return AuthStyleInHeader
+	}
+
+	for _, preferred := range []string{"client_secret_basic", "client_secret_post"} {
+		for _, method := range methods {
+			if method == preferred {
+				return authStyles[method]
+			}
+		}
+	}
+
+	for _, method := range methods {
+		if style, ok := authStyles[method]; ok {
+			return style
+		}
+	}
+
+	return AuthStyleAutoDetect
+}
+
+// authStyles maps the registered client authentication method names to the
+// AuthStyle which implements them.
+var authStyles = map[string]AuthStyle{
+	"client_secret_basic":         AuthStyleInHeader,
+	"client_secret_post":          AuthStyleInParams,
+	"private_key_jwt":             AuthStylePrivateKeyJWT,
+	"client_secret_jwt":           AuthStyleClientSecretJWT,
+	"tls_client_auth":             AuthStyleTLSClientAuth,
+	"self_signed_tls_client_auth": AuthStyleSelfSignedTLSClientAuth,
+}
+
+const (
+	// WellKnownOpenIDConfiguration is the OpenID Connect 1.0 Discovery path.
+	WellKnownOpenIDConfiguration = "/.well-known/openid-configuration"
+
+	// WellKnownOAuthAuthorizationServer is the RFC 8414 Authorization Server
+	// Metadata path.
+	WellKnownOAuthAuthorizationServer = "/.well-known/oauth-authorization-server"
+)
+
+// Discover retrieves the metadata of the authorization server identified by
+// issuer. The OpenID Connect 1.0 Discovery document is tried first, followed
+// by the RFC 8414 Authorization Server Metadata document. The 'issuer' of the
+// document must exactly match issuer.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func Discover(ctx context.Context, issuer string) (*ProviderMetadata, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot discover issuer %q: %w", issuer, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("oauth2: cannot discover issuer %q: the issuer must be an absolute URL without a query or fragment", issuer)
+	}
+
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	// OpenID Connect appends the well-known path to the issuer, whereas
+	// RFC 8414 section 3.1 inserts it between the host and the path.
+	uris := []string{
+		u.Scheme + "://" + u.Host + path + WellKnownOpenIDConfiguration,
+		u.Scheme + "://" + u.Host + WellKnownOAuthAuthorizationServer + path,
+	}
+
+	var errs []error
+
+	for _, uri := range uris {
+		m, err := DiscoverURL(ctx, uri, issuer)
+		if err == nil {
+			return m, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// DiscoverURL retrieves the authorization server metadata document from uri.
+// The 'issuer' of the document must exactly match issuer.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func DiscoverURL(ctx context.Context, uri, issuer string) (*ProviderMetadata, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	r, err := internal.Observe(ctx, EndpointKindDiscovery, "", func(ctx context.Context) (*http.Response, error) {
+		return internal.ContextClient(ctx).Do(req.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot fetch metadata: %v", err)
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return nil, fmt.Errorf("oauth2: cannot fetch metadata from %q: %v", uri, r.Status)
+	}
+
+	m := &ProviderMetadata{}
+
+	if err = json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse metadata from %q: %w", uri, err)
+	}
+
+	if m.Issuer != issuer {
+		return nil, fmt.Errorf("oauth2: metadata from %q has issuer %q which does not match the expected issuer %q", uri, m.Issuer, issuer)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,110 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	var issuer string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server/tenant":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{
+				"issuer": %[1]q,
+				"authorization_endpoint": "%[1]s/authorize",
+				"token_endpoint": "%[1]s/token",
+				"device_authorization_endpoint": "%[1]s/device",
+				"pushed_authorization_request_endpoint": "%[1]s/par",
+				"introspection_endpoint": "%[1]s/introspect",
+				"revocation_endpoint": "%[1]s/revoke",
+				"userinfo_endpoint": "%[1]s/userinfo",
+				"jwks_uri": "%[1]s/jwks",
+				"token_endpoint_auth_methods_supported": ["private_key_jwt", "client_secret_post"],
+				"mtls_endpoint_aliases": {"token_endpoint": "https://mtls.example.com/token"},
+				"code_challenge_methods_supported": ["S256"],
+				"custom": "value"
+			}`, issuer)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	issuer = ts.URL + "/tenant"
+
+	m, err := Discover(context.Background(), issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Endpoint{
+		Issuer:           issuer,
+		AuthURL:          issuer + "/authorize",
+		DeviceAuthURL:    issuer + "/device",
+		PushedAuthURL:    issuer + "/par",
+		TokenURL:         issuer + "/token",
+		IntrospectionURL: issuer + "/introspect",
+		RevocationURL:    issuer + "/revoke",
+		UserinfoURL:      issuer + "/userinfo",
+		JWKSURL:          issuer + "/jwks",
+		MTLSEndpointAliases: MTLSEndpointAliases{
+			TokenURL: "https://mtls.example.com/token",
+		},
+		AuthStyle: AuthStyleInParams,
+	}
+	if got := m.Endpoint(); got != want {
+		t.Errorf("Endpoint() = %+v; want %+v", got, want)
+	}
+	if len(m.CodeChallengeMethodsSupported) != 1 || m.CodeChallengeMethodsSupported[0] != "S256" {
+		t.Errorf("CodeChallengeMethodsSupported = %v; want [S256]", m.CodeChallengeMethodsSupported)
+	}
+	if got := m.Extra("custom"); got != "value" {
+		t.Errorf("Extra(custom) = %v; want value", got)
+	}
+
+	if _, err = Discover(context.Background(), issuer+"/"); err == nil {
+		t.Error("Discover with mismatched issuer = nil error; want error")
+	}
+}
+
+func TestDiscoverOpenIDConfiguration(t *testing.T) {
+	var issuer string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			t.Errorf("Unexpected discovery URL %q", r.URL)
+		}
+		fmt.Fprintf(w, `{"issuer": %q, "token_endpoint": "%[1]s/token"}`, issuer)
+	}))
+	defer ts.Close()
+	issuer = ts.URL
+
+	m, err := Discover(context.Background(), issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := m.Endpoint(); e.TokenURL != issuer+"/token" || e.AuthStyle != AuthStyleInHeader {
+		t.Errorf("Unexpected endpoint %+v", e)
+	}
+}
+
+func TestAuthStyleFromMethods(t *testing.T) {
+	tests := []struct {
+		methods []string
+		want    AuthStyle
+	}{
+		{nil, AuthStyleInHeader},
+		{[]string{"client_secret_post", "client_secret_basic"}, AuthStyleInHeader},
+		{[]string{"private_key_jwt", "client_secret_post"}, AuthStyleInParams},
+		{[]string{"private_key_jwt"}, AuthStylePrivateKeyJWT},
+		{[]string{"none"}, AuthStyleAutoDetect},
+	}
+	for _, tt := range tests {
+		if got := authStyleFromMethods(tt.methods); got != tt.want {
+			t.Errorf("authStyleFromMethods(%v) = %v; want %v", tt.methods, got, tt.want)
+		}
+	}
+}
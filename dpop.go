@@ -0,0 +1,143 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// DPoP creates the DPoP proof JWTs which sender-constrain tokens to the key
+// of the client as described in RFC 9449. It also remembers the nonces the
+// servers provide per origin so they're included in subsequent proofs.
+//
+// A DPoP is safe for concurrent use and should be shared by the Config and
+// every Transport using tokens bound to its key.
+type DPoP struct {
+	signer     jws.Signer
+	alg        string
+	jwk        map[string]any
+	thumbprint string
+
+	mu     sync.Mutex // guards nonces
+	nonces map[string]string
+}
+
+// NewDPoP returns a DPoP which signs proofs with key using the JWS algorithm
+// alg. If alg is empty, the algorithm is derived from the key. Tokens are bound
+// to the thumbprint of key's public key, so the DPoP must be kept for as long
+// as they're used.
+func NewDPoP(key crypto.Signer, alg string) (*DPoP, error) {
+	signer, alg, err := jws.NewSigner(alg, key)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot create DPoP signer: %w", err)
+	}
+
+	jwk, err := jws.PublicJWK(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot create DPoP signer: %w", err)
+	}
+
+	thumbprint, err := jws.Thumbprint(jwk)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot create DPoP signer: %w", err)
+	}
+
+	return &DPoP{
+		signer:     signer,
+		alg:        alg,
+		jwk:        jwk,
+		thumbprint: thumbprint,
+		nonces:     map[string]string{},
+	}, nil
+}
+
+// dpopClaims are the claims of a DPoP proof.
+// https://datatracker.ietf.org/doc/html/rfc9449#section-4.2
+type dpopClaims struct {
+	JTI   string `json:"jti"`
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	IAT   int64  `json:"iat"`
+	ATH   string `json:"ath,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// Proof returns a DPoP proof for a request with method to uri, including the
+// most recent nonce provided by the origin of uri. When accessToken is not
+// empty the proof is bound to it with the 'ath' claim.
+func (d *DPoP) Proof(method, uri, accessToken string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("oauth2: cannot create DPoP proof: %w", err)
+	}
+
+	jti, err := getRandomBytes(32, charsetRFC3986Unreserved)
+	if err != nil {
+		return "", fmt.Errorf("oauth2: cannot create DPoP proof: %w", err)
+	}
+
+	claims := &dpopClaims{
+		JTI: string(jti),
+		HTM: method,
+		// The htu claim excludes the query and fragment parts of the URI.
+		HTU:   (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path, RawPath: u.RawPath}).String(),
+		IAT:   timeNow().Unix(),
+		Nonce: d.nonce(u),
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims.ATH = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	header := map[string]any{
+		"alg": d.alg,
+		"typ": "dpop+jwt",
+		"jwk": d.jwk,
+	}
+
+	return jws.EncodeClaimsWithSigner(header, claims, d.signer)
+}
+
+// SetNonce records nonce as the DPoP nonce provided by the origin of uri.
+func (d *DPoP) SetNonce(uri, nonce string) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nonces[origin(u)] = nonce
+}
+
+func (d *DPoP) nonce(u *url.URL) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.nonces[origin(u)]
+}
+
+// Thumbprint returns the RFC 7638 JWK SHA-256 thumbprint of the DPoP public key.
+func (d *DPoP) Thumbprint() string {
+	return d.thumbprint
+}
+
+// AuthCodeOption returns an AuthCodeOption which binds the authorization code
+// to the DPoP key using the 'dpop_jkt' parameter. It should be passed to
+// Config.AuthCodeURL or Config.PushedAuth.
+//
+// See https://datatracker.ietf.org/doc/html/rfc9449#section-10.
+func (d *DPoP) AuthCodeOption() AuthCodeOption {
+	return SetAuthURLParam("dpop_jkt", d.thumbprint)
+}
+
+func origin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
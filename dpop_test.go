@@ -0,0 +1,121 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeDPoPProof(t *testing.T, proof string) (header, claims map[string]any) {
+	t.Helper()
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Unexpected DPoP proof %q", proof)
+	}
+	b, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(b, &header); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(b, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return header, claims
+}
+
+func TestDPoP(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dpop, err := NewDPoP(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokenRequests, resourceRequests int
+	var serverURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, claims := decodeDPoPProof(t, r.Header.Get("DPoP"))
+		if header["typ"] != "dpop+jwt" || header["alg"] != "ES256" || header["jwk"] == nil {
+			t.Errorf("Unexpected DPoP header %v", header)
+		}
+		if claims["htm"] != r.Method || claims["htu"] != serverURL+r.URL.Path {
+			t.Errorf("Unexpected DPoP htm, htu %v, %v", claims["htm"], claims["htu"])
+		}
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if tokenRequests == 1 {
+				w.Header().Set("DPoP-Nonce", "nonce-1")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error": "use_dpop_nonce"}`)
+				return
+			}
+			if claims["nonce"] != "nonce-1" {
+				t.Errorf("Unexpected DPoP nonce %v", claims["nonce"])
+			}
+			if claims["ath"] != nil {
+				t.Errorf("Unexpected DPoP ath %v", claims["ath"])
+			}
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"access_token": "ACCESS_TOKEN", "token_type": "DPoP"}`)
+		case "/resource":
+			resourceRequests++
+			if got, want := r.Header.Get("Authorization"), "DPoP ACCESS_TOKEN"; got != want {
+				t.Errorf("Authorization header = %q; want %q", got, want)
+			}
+			sum := sha256.Sum256([]byte("ACCESS_TOKEN"))
+			if claims["ath"] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+				t.Errorf("Unexpected DPoP ath %v", claims["ath"])
+			}
+			if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+				t.Errorf("Unexpected request body %q", body)
+			}
+			if resourceRequests == 1 {
+				w.Header().Set("DPoP-Nonce", "nonce-2")
+				w.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if claims["nonce"] != "nonce-2" {
+				t.Errorf("Unexpected DPoP nonce %v", claims["nonce"])
+			}
+		default:
+			t.Errorf("Unexpected request URL %q", r.URL)
+		}
+	}))
+	defer ts.Close()
+	serverURL = ts.URL
+
+	conf := newConf(ts.URL)
+	conf.DPoP = dpop
+	tok, err := conf.Exchange(context.Background(), "exchange-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Type() != "DPoP" {
+		t.Errorf("Token type = %q; want %q", tok.Type(), "DPoP")
+	}
+	resp, err := conf.Client(context.Background(), tok).Post(ts.URL+"/resource?q=1", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+	if tokenRequests != 2 || resourceRequests != 2 {
+		t.Errorf("requests = %d, %d; want 2, 2", tokenRequests, resourceRequests)
+	}
+}
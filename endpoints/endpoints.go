@@ -284,6 +284,7 @@ func Authelia(issuer *url.URL) oauth2.Endpoint {
 	api := issuer.JoinPath("api", "oidc")
 
 	return oauth2.Endpoint{
+		Issuer:           issuer.String(),
 		AuthURL:          api.JoinPath("authorization").String(),
 		DeviceAuthURL:    api.JoinPath("device-authorization").String(),
 		PushedAuthURL:    api.JoinPath("pushed-authorization-request").String(),
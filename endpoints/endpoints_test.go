@@ -37,7 +37,7 @@ func TestAWSCognitoEndpoint(t *testing.T) {
 		t.Run(tt.in, func(t *testing.T) {
 			endpoint := AWSCognito(tt.in)
 			if endpoint != tt.out {
-				t.Errorf("got %q, want %q", endpoint, tt.out)
+				t.Errorf("got %+v, want %+v", endpoint, tt.out)
 			}
 		})
 	}
@@ -58,6 +58,7 @@ func TestAuthelia(t *testing.T) {
 			"ShouldHandleExample",
 			&url.URL{Scheme: "https", Host: "auth.example.com"},
 			oauth2.Endpoint{
+				Issuer:           "https://auth.example.com",
 				AuthURL:          "https://auth.example.com/api/oidc/authorization",
 				DeviceAuthURL:    "https://auth.example.com/api/oidc/device-authorization",
 				PushedAuthURL:    "https://auth.example.com/api/oidc/pushed-authorization-request",
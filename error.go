@@ -3,6 +3,8 @@ package oauth2
 import (
 	"fmt"
 	"net/http"
+
+	"authelia.com/client/oauth2/internal"
 )
 
 // Error interface for most error types, particularly new ones.
@@ -49,6 +51,19 @@ func (r *BaseError) GetBody() []byte {
 	return r.Body
 }
 
+// Temporary indicates that the server is temporarily unable to handle the
+// request, which may be retried, as the response has one of the following
+// status codes: 429, 502, 503 or 504, or RFC 6749's 'temporarily_unavailable'
+// error code.
+func (r *BaseError) Temporary() bool {
+	var code int
+	if r.Response != nil {
+		code = r.Response.StatusCode
+	}
+
+	return internal.IsTemporary(code, r.ErrorCode)
+}
+
 func (r *BaseError) Error() string {
 	if r.ErrorCode != "" {
 		s := fmt.Sprintf("oauth2: %q", r.ErrorCode)
@@ -65,5 +80,7 @@ func (r *BaseError) Error() string {
 		return fmt.Sprintf("oauth2: request failed")
 	}
 
-	return fmt.Sprintf("oauth2: request failed: %v\nResponse: %s", r.Response.Status, r.Body)
+	// The body isn't included as it may echo tokens or credentials. It's
+	// available from GetBody.
+	return fmt.Sprintf("oauth2: request failed: %v", r.Response.Status)
 }
@@ -230,7 +230,7 @@ func FindDefaultCredentialsWithParams(ctx context.Context, params CredentialsPar
 		}
 		return &Credentials{
 			ProjectID:              id,
-			TokenSource:            computeTokenSource("", params.EarlyTokenRefresh, params.Scopes...),
+			TokenSource:            computeTokenSource(ctx, "", params.EarlyTokenRefresh, params.Scopes...),
 			UniverseDomainProvider: universeDomainProvider,
 			universeDomain:         params.UniverseDomain,
 		}, nil
@@ -285,7 +285,7 @@ func TestComputeUniverseDomain(t *testing.T) {
 	// Copied from FindDefaultCredentialsWithParams, metadata.OnGCE() = true block
 	creds := &Credentials{
 		ProjectID:              "fake_project",
-		TokenSource:            computeTokenSource("", params.EarlyTokenRefresh, params.Scopes...),
+		TokenSource:            computeTokenSource(context.Background(), "", params.EarlyTokenRefresh, params.Scopes...),
 		UniverseDomainProvider: universeDomainProvider,
 		universeDomain:         params.UniverseDomain, // empty
 	}
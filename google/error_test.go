@@ -47,7 +47,7 @@ func TestAuthenticationError_Temporary(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ae := &AuthenticationError{
 				err: &oauth2.RetrieveError{
-					&oauth2.BaseError{
+					BaseError: &oauth2.BaseError{
 						Response: &http.Response{
 							StatusCode: tt.code,
 						},
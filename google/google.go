@@ -19,6 +19,7 @@ import (
 	"authelia.com/client/oauth2"
 	"authelia.com/client/oauth2/google/externalaccount"
 	"authelia.com/client/oauth2/google/internal/externalaccountauthorizeduser"
+	"authelia.com/client/oauth2/internal"
 	"authelia.com/client/oauth2/internal/jwt"
 )
 
@@ -256,14 +257,17 @@ func ComputeTokenSource(account string, scope ...string) oauth2.TokenSource {
 	// refresh 3 minutes and 45 seconds early. The shortest MDS cache is currently 4 minutes, so any
 	// refreshes earlier are a waste of compute.
 	earlyExpirySecs := 225 * time.Second
-	return computeTokenSource(account, earlyExpirySecs, scope...)
+	return computeTokenSource(context.Background(), account, earlyExpirySecs, scope...)
 }
 
-func computeTokenSource(account string, earlyExpiry time.Duration, scope ...string) oauth2.TokenSource {
-	return oauth2.ReuseTokenSourceWithExpiry(nil, computeSource{account: account, scopes: scope}, earlyExpiry)
+func computeTokenSource(ctx context.Context, account string, earlyExpiry time.Duration, scope ...string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSourceWithExpiry(nil, computeSource{ctx: ctx, account: account, scopes: scope}, earlyExpiry)
 }
 
 type computeSource struct {
+	// ctx is only used for the hooks observing the metadata requests, which
+	// are then made with its HTTP client.
+	ctx     context.Context
 	account string
 	scopes  []string
 }
@@ -282,7 +286,14 @@ func (cs computeSource) Token() (*oauth2.Token, error) {
 		v.Set("scopes", strings.Join(cs.scopes, ","))
 		tokenURI = tokenURI + "?" + v.Encode()
 	}
-	tokenJSON, err := metadata.Get(tokenURI)
+	tokenJSON, err := internal.Observe(cs.ctx, internal.EndpointKindMetadata, "", func(ctx context.Context) (string, error) {
+		if internal.ContextHooks(ctx) == nil {
+			return metadata.Get(tokenURI)
+		}
+
+		// The client of the observed context records the status code.
+		return metadata.NewClient(internal.ContextClient(ctx)).GetWithContext(ctx, tokenURI)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -5,10 +5,13 @@
 package google
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"authelia.com/client/oauth2"
 )
 
 var webJSONKey = []byte(`
@@ -157,3 +160,32 @@ func TestComputeTokenSource(t *testing.T) {
 		t.Errorf("ts.Token() = %v", err)
 	}
 }
+
+type recordingHooks struct {
+	infos []*oauth2.RequestInfo
+}
+
+func (h *recordingHooks) RequestStart(ctx context.Context, info *oauth2.RequestInfo) context.Context {
+	return ctx
+}
+
+func (h *recordingHooks) RequestDone(ctx context.Context, info *oauth2.RequestInfo) {
+	h.infos = append(h.infos, info)
+}
+
+func TestComputeTokenSourceHooks(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"Sample.Access.Token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer s.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(s.URL, "http://"))
+
+	hooks := &recordingHooks{}
+	ts := computeTokenSource(oauth2.ContextWithHooks(context.Background(), hooks), "", 0)
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks.infos) != 1 || hooks.infos[0].Endpoint != oauth2.EndpointKindMetadata || hooks.infos[0].StatusCode != http.StatusOK {
+		t.Errorf("Observed requests = %+v; want a metadata request with status 200", hooks.infos)
+	}
+}
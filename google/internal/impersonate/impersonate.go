@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/internal"
 )
 
 // generateAccesstokenReq is used for service account impersonation
@@ -67,7 +68,6 @@ func (its ImpersonateTokenSource) Token() (*oauth2.Token, error) {
 	if err != nil {
 		return nil, fmt.Errorf("oauth2/google: unable to marshal request: %v", err)
 	}
-	client := oauth2.NewClient(its.Ctx, its.Ts)
 	req, err := http.NewRequest(http.MethodPost, its.URL, bytes.NewReader(b))
 	if err != nil {
 		return nil, fmt.Errorf("oauth2/google: unable to create impersonation request: %v", err)
@@ -75,7 +75,9 @@ func (its ImpersonateTokenSource) Token() (*oauth2.Token, error) {
 	req = req.WithContext(its.Ctx)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := internal.Observe(its.Ctx, internal.EndpointKindImpersonation, "", func(ctx context.Context) (*http.Response, error) {
+		return oauth2.NewClient(ctx, its.Ts).Do(req.WithContext(ctx))
+	})
 	if err != nil {
 		return nil, fmt.Errorf("oauth2/google: unable to generate access token: %v", err)
 	}
@@ -85,7 +87,7 @@ func (its ImpersonateTokenSource) Token() (*oauth2.Token, error) {
 		return nil, fmt.Errorf("oauth2/google: unable to read body: %v", err)
 	}
 	if c := resp.StatusCode; c < 200 || c > 299 {
-		return nil, fmt.Errorf("oauth2/google: status code %d: %s", c, body)
+		return nil, fmt.Errorf("oauth2/google: status code %d", c)
 	}
 
 	var accessTokenResp impersonateTokenResponse
@@ -15,6 +15,7 @@ import (
 	"strings"
 
 	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/internal"
 )
 
 func defaultHeader() http.Header {
@@ -58,7 +59,6 @@ func makeRequest(ctx context.Context, endpoint string, data url.Values, authenti
 	if headers == nil {
 		headers = defaultHeader()
 	}
-	client := oauth2.NewClient(ctx, nil)
 	authentication.InjectAuthentication(data, headers)
 	encodedData := data.Encode()
 
@@ -74,7 +74,9 @@ func makeRequest(ctx context.Context, endpoint string, data url.Values, authenti
 	}
 	req.Header.Add("Content-Length", strconv.Itoa(len(encodedData)))
 
-	resp, err := client.Do(req)
+	resp, err := internal.Observe(ctx, internal.EndpointKindSTS, data.Get("grant_type"), func(ctx context.Context) (*http.Response, error) {
+		return oauth2.NewClient(ctx, nil).Do(req.WithContext(ctx))
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("oauth2/google: invalid response from Secure Token Server: %v", err)
@@ -86,7 +88,7 @@ func makeRequest(ctx context.Context, endpoint string, data url.Values, authenti
 		return nil, err
 	}
 	if c := resp.StatusCode; c < 200 || c > 299 {
-		return nil, fmt.Errorf("oauth2/google: status code %d: %s", c, body)
+		return nil, fmt.Errorf("oauth2/google: status code %d", c)
 	}
 	var stsResp Response
 	err = json.Unmarshal(body, &stsResp)
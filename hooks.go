@@ -0,0 +1,127 @@
+package oauth2
+
+import (
+	"context"
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+)
+
+// The kinds of endpoints of RequestInfo.Endpoint.
+const (
+	EndpointKindToken           = internal.EndpointKindToken
+	EndpointKindPushedAuth      = internal.EndpointKindPushedAuth
+	EndpointKindDeviceAuth      = internal.EndpointKindDeviceAuth
+	EndpointKindBackchannelAuth = internal.EndpointKindBackchannelAuth
+	EndpointKindRevocation      = internal.EndpointKindRevocation
+	EndpointKindIntrospection   = internal.EndpointKindIntrospection
+	EndpointKindUserInfo        = internal.EndpointKindUserInfo
+	EndpointKindDiscovery       = internal.EndpointKindDiscovery
+	EndpointKindJWKS            = internal.EndpointKindJWKS
+
+	// EndpointKindSTS, EndpointKindImpersonation and EndpointKindMetadata are
+	// the Google Security Token Service, service account impersonation and
+	// compute metadata server endpoints.
+	EndpointKindSTS           = internal.EndpointKindSTS
+	EndpointKindImpersonation = internal.EndpointKindImpersonation
+	EndpointKindMetadata      = internal.EndpointKindMetadata
+)
+
+// RequestInfo describes an HTTP request made by this package, or a token
+// returned from a cache rather than requested, to Hooks.
+type RequestInfo struct {
+	// Endpoint is the kind of endpoint requested, such as EndpointKindToken.
+	Endpoint string
+
+	// GrantType is the 'grant_type' parameter of token requests, such as
+	// "refresh_token". It's empty for cached tokens, as the grant they were
+	// obtained with isn't known to the cache.
+	GrantType string
+
+	// Start is when the request started.
+	Start time.Time
+
+	// Duration is how long the request took. It's set once it's done.
+	Duration time.Duration
+
+	// StatusCode is the HTTP status code of the response, or zero if there's
+	// none. It's set once the request is done.
+	StatusCode int
+
+	// ErrorCode is RFC 6749's 'error' parameter of the response, if any. It's
+	// set once the request is done.
+	ErrorCode string
+
+	// Err is the error the request failed with, if any. It's set once the
+	// request is done.
+	Err error
+
+	// Cached is set when the token was returned from a cache, in which case no
+	// request was made.
+	Cached bool
+}
+
+// Hooks observe the HTTP requests made to the authorization server and the
+// other endpoints used by this package and its subpackages, such as to create
+// tracing spans, record metrics or log them. Hooks are configured with
+// Config.Hooks, or for every request made with a context by ContextWithHooks.
+//
+// Each request attempt, including those which are retried, is observed
+// separately. The hooks may be called concurrently.
+type Hooks interface {
+	// RequestStart is called before a request is made. The returned context,
+	// which must derive from ctx, is used for the request and passed to
+	// RequestDone.
+	RequestStart(ctx context.Context, info *RequestInfo) context.Context
+
+	// RequestDone is called once the request is done.
+	RequestDone(ctx context.Context, info *RequestInfo)
+}
+
+// ContextWithHooks returns a copy of ctx with the hooks h, which are called
+// around the requests made with it.
+func ContextWithHooks(ctx context.Context, h Hooks) context.Context {
+	return internal.ContextWithHooks(ctx, internalHooks(h))
+}
+
+// internalHooks returns the internal.Hooks calling h, or nil if h is nil.
+func internalHooks(h Hooks) internal.Hooks {
+	if h == nil {
+		return nil
+	}
+
+	return hooksAdapter{h}
+}
+
+type hooksAdapter struct {
+	h Hooks
+}
+
+func (a hooksAdapter) RequestStart(ctx context.Context, info *internal.RequestInfo) context.Context {
+	return a.h.RequestStart(ctx, (*RequestInfo)(info))
+}
+
+func (a hooksAdapter) RequestDone(ctx context.Context, info *internal.RequestInfo) {
+	info.Err = errorFromInternal(info.Err)
+	a.h.RequestDone(ctx, (*RequestInfo)(info))
+}
+
+// errorFromInternal maps the errors of the internal package to those of this
+// package, as they're returned to the caller.
+func errorFromInternal(err error) error {
+	switch e := err.(type) {
+	case *internal.RetrieveError:
+		return &RetrieveError{BaseError: (*BaseError)(e)}
+	case *internal.RevokeError:
+		return &RevokeError{(*BaseError)(e)}
+	case *internal.IntrospectionError:
+		return &IntrospectionError{BaseError: (*BaseError)(e)}
+	default:
+		return err
+	}
+}
+
+// hooksContext returns a copy of ctx with the Hooks of c, if any.
+func (c *Config) hooksContext(ctx context.Context) context.Context {
+	return internal.ContextWithHooks(ctx, internalHooks(c.Hooks))
+}
@@ -0,0 +1,107 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hooksKey struct{}
+
+type recordingHooks struct {
+	mu    sync.Mutex
+	infos []RequestInfo
+}
+
+func (h *recordingHooks) RequestStart(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, hooksKey{}, info)
+}
+
+func (h *recordingHooks) RequestDone(ctx context.Context, info *RequestInfo) {
+	if ctx.Value(hooksKey{}) != info {
+		panic("RequestDone context wasn't returned by RequestStart")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.infos = append(h.infos, *info)
+}
+
+func TestConfigHooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("grant_type") == "refresh_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"ACCESS_TOKEN","token_type":"bearer","refresh_token":"REFRESH_TOKEN","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	hooks := &recordingHooks{}
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Hooks = hooks
+
+	tok, err := conf.Exchange(context.Background(), "CODE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := conf.TokenSource(context.Background(), tok)
+	if _, err = src.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	tok.Expiry = time.Now().Add(-time.Hour)
+	if _, err = conf.TokenSource(context.Background(), tok).Token(); err == nil {
+		t.Fatal("Token succeeded; want an error")
+	}
+
+	if len(hooks.infos) != 3 {
+		t.Fatalf("Hooks were called for %d requests; want 3", len(hooks.infos))
+	}
+
+	exchange, cached, refresh := hooks.infos[0], hooks.infos[1], hooks.infos[2]
+
+	if exchange.Endpoint != EndpointKindToken || exchange.GrantType != "authorization_code" || exchange.StatusCode != http.StatusOK || exchange.Err != nil || exchange.Cached || exchange.Start.IsZero() || exchange.Duration <= 0 {
+		t.Errorf("Exchange info = %+v", exchange)
+	}
+
+	if cached.Endpoint != EndpointKindToken || !cached.Cached || cached.GrantType != "" || cached.StatusCode != 0 {
+		t.Errorf("Cached info = %+v", cached)
+	}
+
+	var rErr *RetrieveError
+	if refresh.GrantType != "refresh_token" || refresh.StatusCode != http.StatusBadRequest || refresh.ErrorCode != "invalid_grant" || !errors.As(refresh.Err, &rErr) {
+		t.Errorf("Refresh info = %+v", refresh)
+	}
+}
+
+func TestContextWithHooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code":"DEVICE_CODE","user_code":"USER_CODE","verification_uri":"https://example.com/device","expires_in":300}`))
+	}))
+	defer ts.Close()
+
+	hooks := &recordingHooks{}
+	conf := &Config{ClientID: "CLIENT_ID", Endpoint: Endpoint{DeviceAuthURL: ts.URL}}
+
+	if _, err := conf.DeviceAuth(ContextWithHooks(context.Background(), hooks)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hooks.infos) != 1 {
+		t.Fatalf("Hooks were called for %d requests; want 1", len(hooks.infos))
+	}
+
+	if info := hooks.infos[0]; info.Endpoint != EndpointKindDeviceAuth || info.StatusCode != http.StatusOK || info.Err != nil {
+		t.Errorf("Device authorization info = %+v", info)
+	}
+}
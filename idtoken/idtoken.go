@@ -0,0 +1,375 @@
+// Package idtoken implements the verification of OpenID Connect 1.0 ID Tokens
+// as described in OpenID Connect Core 1.0 section 3.1.3.7.
+//
+// See https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
+package idtoken // import "authelia.com/client/oauth2/idtoken"
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+	"authelia.com/client/oauth2/jwks"
+)
+
+// DefaultClockSkew is the clock skew allowed when a Verifier's ClockSkew is
+// zero.
+const DefaultClockSkew = jws.DefaultClockSkew
+
+// ErrExpired is returned, wrapped, when the ID Token has expired.
+var ErrExpired = errors.New("idtoken: token is expired")
+
+// Token is a verified ID Token.
+type Token struct {
+	// Issuer is the 'iss' claim.
+	Issuer string `json:"iss"`
+	// Subject is the 'sub' claim.
+	Subject string `json:"sub"`
+	// Audience is the 'aud' claim.
+	Audience []string `json:"-"`
+	// Expiry is the 'exp' claim.
+	Expiry time.Time `json:"-"`
+	// IssuedAt is the 'iat' claim.
+	IssuedAt time.Time `json:"-"`
+	// NotBefore is the optional 'nbf' claim.
+	NotBefore time.Time `json:"-"`
+	// AuthTime is the optional 'auth_time' claim.
+	AuthTime time.Time `json:"-"`
+	// Nonce is the optional 'nonce' claim.
+	Nonce string `json:"nonce,omitempty"`
+	// AuthenticationContextClassReference is the optional 'acr' claim.
+	AuthenticationContextClassReference string `json:"acr,omitempty"`
+	// AuthenticationMethodsReferences is the optional 'amr' claim.
+	AuthenticationMethodsReferences []string `json:"amr,omitempty"`
+	// AuthorizedParty is the optional 'azp' claim.
+	AuthorizedParty string `json:"azp,omitempty"`
+	// AccessTokenHash is the optional 'at_hash' claim.
+	AccessTokenHash string `json:"at_hash,omitempty"`
+	// CodeHash is the optional 'c_hash' claim.
+	CodeHash string `json:"c_hash,omitempty"`
+	// SessionID is the optional 'sid' claim.
+	SessionID string `json:"sid,omitempty"`
+
+	// Algorithm is the JWS algorithm the token was signed with.
+	Algorithm string `json:"-"`
+
+	// raw is the JSON object containing every claim of the token.
+	raw []byte
+
+	// claims contains every claim of the token.
+	claims map[string]any
+}
+
+func (t *Token) UnmarshalJSON(data []byte) (err error) {
+	type Alias Token
+
+	aux := &struct {
+		Audience  json.RawMessage `json:"aud"`
+		Expiry    jws.NumericDate `json:"exp"`
+		IssuedAt  jws.NumericDate `json:"iat"`
+		NotBefore jws.NumericDate `json:"nbf"`
+		AuthTime  jws.NumericDate `json:"auth_time"`
+		*Alias
+	}{
+		Alias: (*Alias)(t),
+	}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if t.Audience, err = jws.ParseAudience(aux.Audience); err != nil {
+		return fmt.Errorf("idtoken: cannot parse 'aud' claim: %w", err)
+	}
+
+	t.Expiry = aux.Expiry.Time()
+	t.IssuedAt = aux.IssuedAt.Time()
+	t.NotBefore = aux.NotBefore.Time()
+	t.AuthTime = aux.AuthTime.Time()
+	t.raw = append([]byte(nil), data...)
+
+	return json.Unmarshal(data, &t.claims)
+}
+
+// Claims unmarshals the raw claims of the token into v, which allows decoding
+// claims not represented by the Token fields.
+func (t *Token) Claims(v any) error {
+	if t.raw == nil {
+		return errors.New("idtoken: token has no claims")
+	}
+
+	return json.Unmarshal(t.raw, v)
+}
+
+// Extra returns a claim of the token, including any which are not represented
+// by the Token fields.
+func (t *Token) Extra(key string) any {
+	return t.claims[key]
+}
+
+// Verifier verifies ID Tokens issued to a client.
+type Verifier struct {
+	// ClientID is the client the ID Tokens must be issued to.
+	ClientID string
+
+	// Issuer is the issuer identifier the ID Tokens must be issued by.
+	Issuer string
+
+	// KeySet is the key set of the issuer used to verify the signatures.
+	KeySet *jwks.KeySet
+
+	// Algorithms optionally restricts the accepted JWS algorithms. If empty,
+	// all supported asymmetric algorithms are accepted.
+	Algorithms []string
+
+	// ClockSkew is the allowed difference between the clocks of the client
+	// and the issuer. If zero, DefaultClockSkew is used.
+	ClockSkew time.Duration
+
+	// DecryptionKey is the client's optional private key used to decrypt
+	// encrypted ID Tokens.
+	DecryptionKey crypto.PrivateKey
+
+	// Now optionally returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewVerifier returns a Verifier for the ID Tokens issued to the client of
+// config. The issuer is taken from its Endpoint, and the key set is the
+// Config.KeySet.
+func NewVerifier(config *oauth2.Config) *Verifier {
+	v := &Verifier{
+		ClientID:      config.ClientID,
+		Issuer:        config.Endpoint.Issuer,
+		DecryptionKey: config.DecryptionKey,
+	}
+
+	if ks, err := config.KeySet(); err == nil {
+		v.KeySet = ks
+	}
+
+	return v
+}
+
+// An Option is passed to Verifier.Verify to validate request specific claims.
+type Option interface {
+	apply(o *options)
+}
+
+type options struct {
+	nonce       string
+	accessToken string
+	code        string
+	maxAge      time.Duration
+}
+
+type optionFunc func(o *options)
+
+func (f optionFunc) apply(o *options) { f(o) }
+
+// NonceOption builds an Option which requires the 'nonce' claim to equal the
+// nonce sent in the authentication request.
+func NonceOption(nonce string) Option {
+	return optionFunc(func(o *options) { o.nonce = nonce })
+}
+
+// AccessTokenOption builds an Option which requires the 'at_hash' claim, when
+// present, to match accessToken.
+func AccessTokenOption(accessToken string) Option {
+	return optionFunc(func(o *options) { o.accessToken = accessToken })
+}
+
+// CodeOption builds an Option which requires the 'c_hash' claim, when present,
+// to match the authorization code.
+func CodeOption(code string) Option {
+	return optionFunc(func(o *options) { o.code = code })
+}
+
+// MaxAgeOption builds an Option which requires the 'auth_time' claim to be
+// within maxAge, as sent with the 'max_age' parameter of the authentication
+// request.
+func MaxAgeOption(maxAge time.Duration) Option {
+	return optionFunc(func(o *options) { o.maxAge = maxAge })
+}
+
+// VerifyToken verifies the ID Token of token, such as one returned by
+// oauth2.Config.Exchange or a refresh, and validates its 'at_hash' claim
+// against the access token.
+func (v *Verifier) VerifyToken(ctx context.Context, token *oauth2.Token, opts ...Option) (*Token, error) {
+	if token == nil || token.IDToken == "" {
+		return nil, errors.New("idtoken: the token has no ID Token")
+	}
+
+	return v.Verify(ctx, token.IDToken, append([]Option{AccessTokenOption(token.AccessToken)}, opts...)...)
+}
+
+// Verify verifies the signature and the claims of the raw ID Token and returns
+// its claims. Encrypted ID Tokens are decrypted with the DecryptionKey.
+//
+// The provided context optionally controls which HTTP client is used to
+// retrieve the key set. See the oauth2.HTTPClient variable.
+func (v *Verifier) Verify(ctx context.Context, rawIDToken string, opts ...Option) (*Token, error) {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	if v.Issuer == "" {
+		return nil, errors.New("idtoken: no issuer is configured")
+	}
+
+	if v.KeySet == nil {
+		return nil, jwks.ErrNoKeySet
+	}
+
+	if jwe.IsEncrypted(rawIDToken) {
+		if v.DecryptionKey == nil {
+			return nil, errors.New("idtoken: token is encrypted but no decryption key is configured")
+		}
+
+		plaintext, _, err := jwe.Decrypt(rawIDToken, v.DecryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("idtoken: cannot decrypt token: %w", err)
+		}
+
+		rawIDToken = string(plaintext)
+	}
+
+	header, err := jws.DecodeHeader(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("idtoken: malformed token: %w", err)
+	}
+
+	payload, err := v.KeySet.Verify(ctx, rawIDToken, v.Algorithms...)
+	if err != nil {
+		return nil, fmt.Errorf("idtoken: cannot verify token: %w", err)
+	}
+
+	t := &Token{Algorithm: header.Algorithm}
+
+	if err = json.Unmarshal(payload, t); err != nil {
+		return nil, fmt.Errorf("idtoken: cannot parse token claims: %w", err)
+	}
+
+	if err = v.validate(t, o); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (v *Verifier) validate(t *Token, o *options) error {
+	if t.Issuer != v.Issuer {
+		return fmt.Errorf("idtoken: 'iss' claim %q does not match the expected issuer %q", t.Issuer, v.Issuer)
+	}
+
+	if t.Subject == "" {
+		return errors.New("idtoken: token has no 'sub' claim")
+	}
+
+	if !contains(t.Audience, v.ClientID) {
+		return fmt.Errorf("idtoken: 'aud' claim %q does not contain the client id %q", t.Audience, v.ClientID)
+	}
+
+	if t.AuthorizedParty != "" && t.AuthorizedParty != v.ClientID {
+		return fmt.Errorf("idtoken: 'azp' claim %q does not match the client id %q", t.AuthorizedParty, v.ClientID)
+	}
+
+	if len(t.Audience) > 1 && t.AuthorizedParty == "" {
+		return errors.New("idtoken: token has multiple audiences but no 'azp' claim")
+	}
+
+	now, skew := v.now(), v.clockSkew()
+
+	if t.Expiry.IsZero() {
+		return errors.New("idtoken: token has no 'exp' claim")
+	}
+
+	if now.After(t.Expiry.Add(skew)) {
+		return fmt.Errorf("%w: expired at %v", ErrExpired, t.Expiry)
+	}
+
+	if t.IssuedAt.IsZero() {
+		return errors.New("idtoken: token has no 'iat' claim")
+	}
+
+	if t.IssuedAt.After(now.Add(skew)) {
+		return fmt.Errorf("idtoken: token is issued in the future at %v", t.IssuedAt)
+	}
+
+	if !t.NotBefore.IsZero() && t.NotBefore.After(now.Add(skew)) {
+		return fmt.Errorf("idtoken: token is not valid before %v", t.NotBefore)
+	}
+
+	if o.nonce != "" && t.Nonce != o.nonce {
+		return fmt.Errorf("idtoken: 'nonce' claim %q does not match the expected nonce", t.Nonce)
+	}
+
+	if o.maxAge > 0 {
+		if t.AuthTime.IsZero() {
+			return errors.New("idtoken: token has no 'auth_time' claim but a max age is required")
+		}
+
+		if now.After(t.AuthTime.Add(o.maxAge + skew)) {
+			return fmt.Errorf("idtoken: authentication at %v exceeds the max age %v", t.AuthTime, o.maxAge)
+		}
+	}
+
+	if err := validateHash("at_hash", t.AccessTokenHash, t.Algorithm, o.accessToken); err != nil {
+		return err
+	}
+
+	return validateHash("c_hash", t.CodeHash, t.Algorithm, o.code)
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+
+	return time.Now()
+}
+
+func (v *Verifier) clockSkew() time.Duration {
+	if v.ClockSkew != 0 {
+		return v.ClockSkew
+	}
+
+	return DefaultClockSkew
+}
+
+// validateHash validates the 'at_hash' or 'c_hash' claim when both the claim
+// and the value it's computed from are present.
+func validateHash(claim, hash, alg, value string) error {
+	if hash == "" || value == "" {
+		return nil
+	}
+
+	expected, err := jws.HalfHash(alg, value)
+	if err != nil {
+		return fmt.Errorf("idtoken: cannot validate '%s' claim: %w", claim, err)
+	}
+
+	if hash != expected {
+		return fmt.Errorf("idtoken: '%s' claim does not match", claim)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package idtoken
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := jws.PublicJWK(key.Public())
+		jwk["kid"] = "key1"
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	}))
+	defer ts.Close()
+
+	now := time.Unix(1700000000, 0)
+	atHash, _ := jws.HalfHash(jws.ES256, "ACCESS_TOKEN")
+	cHash, _ := jws.HalfHash(jws.ES256, "CODE")
+
+	sign := func(modify func(claims map[string]any)) string {
+		claims := map[string]any{
+			"iss":       "https://issuer.example.com",
+			"sub":       "abc",
+			"aud":       "CLIENT_ID",
+			"exp":       now.Add(time.Hour).Unix(),
+			"iat":       now.Unix(),
+			"auth_time": now.Add(-5 * time.Minute).Unix(),
+			"nonce":     "NONCE",
+			"at_hash":   atHash,
+			"c_hash":    cHash,
+			"amr":       []string{"pwd", "otp"},
+			"groups":    []string{"admins"},
+		}
+		if modify != nil {
+			modify(claims)
+		}
+		signer, alg, _ := jws.NewSigner("", key)
+		token, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg, "kid": "key1", "typ": "JWT"}, claims, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	v := NewVerifier(&oauth2.Config{
+		ClientID: "CLIENT_ID",
+		Endpoint: oauth2.Endpoint{Issuer: "https://issuer.example.com", JWKSURL: ts.URL},
+	})
+	v.Now = func() time.Time { return now }
+
+	token, err := v.VerifyToken(context.Background(), &oauth2.Token{AccessToken: "ACCESS_TOKEN", IDToken: sign(nil)}, NonceOption("NONCE"), CodeOption("CODE"), MaxAgeOption(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Subject != "abc" || token.Algorithm != jws.ES256 || len(token.Audience) != 1 || !token.Expiry.Equal(now.Add(time.Hour)) {
+		t.Errorf("Unexpected token %+v", token)
+	}
+	if len(token.AuthenticationMethodsReferences) != 2 {
+		t.Errorf("AuthenticationMethodsReferences = %v; want [pwd otp]", token.AuthenticationMethodsReferences)
+	}
+	var claims struct {
+		Groups []string `json:"groups"`
+	}
+	if err = token.Claims(&claims); err != nil || len(claims.Groups) != 1 {
+		t.Errorf("Claims() = %v, %v; want [admins]", claims.Groups, err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]any)
+		opts   []Option
+		want   string
+	}{
+		{"issuer", func(c map[string]any) { c["iss"] = "https://other.example.com" }, nil, "'iss' claim"},
+		{"audience", func(c map[string]any) { c["aud"] = "OTHER" }, nil, "'aud' claim"},
+		{"azp missing", func(c map[string]any) { c["aud"] = []string{"CLIENT_ID", "OTHER"} }, nil, "'azp' claim"},
+		{"azp mismatch", func(c map[string]any) { c["azp"] = "OTHER" }, nil, "'azp' claim"},
+		{"expired", func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() }, nil, "expired"},
+		{"future", func(c map[string]any) { c["iat"] = now.Add(2 * time.Minute).Unix() }, nil, "future"},
+		{"nbf", func(c map[string]any) { c["nbf"] = now.Add(2 * time.Minute).Unix() }, nil, "not valid before"},
+		{"nonce", nil, []Option{NonceOption("OTHER")}, "'nonce' claim"},
+		{"max age", nil, []Option{MaxAgeOption(time.Second)}, "max age"},
+		{"auth_time", func(c map[string]any) { delete(c, "auth_time") }, []Option{MaxAgeOption(time.Hour)}, "'auth_time' claim"},
+		{"at_hash", nil, []Option{AccessTokenOption("OTHER")}, "'at_hash' claim"},
+		{"c_hash", nil, []Option{CodeOption("OTHER")}, "'c_hash' claim"},
+		{"signature", nil, nil, "cannot verify"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := sign(tt.modify)
+			if tt.name == "signature" {
+				raw = raw[:strings.LastIndex(raw, ".")+1] + strings.Repeat("A", 86)
+			}
+			_, err := v.Verify(context.Background(), raw, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() error = %v; want error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err = v.Verify(context.Background(), sign(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }))
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() error = %v; want ErrExpired", err)
+	}
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// RetrieveBackchannelAuthResponse performs an OpenID Connect Client Initiated
+// Backchannel Authentication (CIBA) request and returns the raw JSON
+// authentication request acknowledgement.
+//
+// Client authentication is handled similar to the token endpoint, including
+// the auth style probing. See https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.7.1.
+func RetrieveBackchannelAuthResponse(ctx context.Context, auth *ClientAuth, backchannelAuthURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) ([]byte, error) {
+	ctx = auth.context(ctx)
+
+	needsAuthStyleProbe := authStyle == 0
+	if needsAuthStyleProbe {
+		if style, ok := styleCache.lookupAuthStyle(backchannelAuthURL); ok {
+			authStyle = style
+			needsAuthStyleProbe = false
+		} else {
+			authStyle = AuthStyleInHeader // the first way we'll try
+		}
+	}
+
+	newRequest := func() (*http.Request, error) {
+		return newPOSTRequest(backchannelAuthURL, auth, v, authStyle)
+	}
+
+	// Each request which reaches the server prompts the end-user, so requests
+	// are only retried when the retry policy allows non-idempotent requests.
+
+	body, err := roundTripWithRetry(ctx, auth, false, EndpointKindBackchannelAuth, "", newRequest, doBackchannelAuthRoundTrip)
+	if err != nil && needsAuthStyleProbe {
+		authStyle = AuthStyleInParams // the second way we'll try
+		body, err = roundTripWithRetry(ctx, auth, false, EndpointKindBackchannelAuth, "", newRequest, doBackchannelAuthRoundTrip)
+	}
+
+	if needsAuthStyleProbe && err == nil {
+		styleCache.setAuthStyle(backchannelAuthURL, authStyle)
+	}
+
+	return body, err
+}
+
+func doBackchannelAuthRoundTrip(ctx context.Context, req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+
+	r, err := ContextClient(ctx).Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot request backchannel authentication: %v", err)
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		// The error response has the same format as the token error response.
+		// https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.13
+		retrieveError := &RetrieveError{
+			Response: r,
+			Body:     body,
+		}
+
+		var ej errorJSON
+		if err = json.Unmarshal(body, &ej); err == nil {
+			retrieveError.ErrorCode = ej.ErrorCode
+			retrieveError.ErrorDescription = ej.ErrorDescription
+			retrieveError.ErrorURI = ej.ErrorURI
+		}
+
+		return nil, retrieveError
+	}
+
+	return body, nil
+}
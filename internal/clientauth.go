@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// ClientAssertionType is the RFC 7523 'client_assertion_type' value used for
+// JWT client authentication.
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// clientAssertionLifetime is how long a client assertion is valid for. It is
+// kept short as assertions are generated per request.
+const clientAssertionLifetime = time.Minute
+
+// ClientAuth contains the credentials used to authenticate the client to the
+// token, PAR, revocation, and introspection endpoints.
+type ClientAuth struct {
+	ClientID     string
+	ClientSecret string
+
+	// PrivateKey signs the client assertion when using AuthStylePrivateKeyJWT.
+	PrivateKey crypto.Signer
+
+	// PrivateKeyID is the optional 'kid' header of the client assertion.
+	PrivateKeyID string
+
+	// Algorithm optionally overrides the JWS algorithm of the client assertion.
+	Algorithm string
+
+	// Audience is the 'aud' claim of the client assertion. If empty, the URL of
+	// the endpoint the request is made to is used.
+	Audience string
+
+	// Certificate is the optional TLS client certificate presented to the
+	// endpoints. It is required when using AuthStyleTLSClientAuth or
+	// AuthStyleSelfSignedTLSClientAuth.
+	Certificate *tls.Certificate
+
+	// DPoP optionally creates the DPoP proofs which bind the issued tokens to
+	// the client's key as described in RFC 9449.
+	DPoP DPoPProver
+
+	// Retry optionally retries requests which fail with a transient error.
+	Retry *RetryPolicy
+
+	// Hooks are optionally called around each request, taking precedence
+	// over the hooks of the context.
+	Hooks Hooks
+}
+
+// context returns a copy of ctx whose HTTP client presents the client
+// certificate and DPoP proofs, if any, and with the hooks, if any.
+func (a *ClientAuth) context(ctx context.Context) context.Context {
+	ctx = ContextWithCertificate(ctx, a.Certificate)
+
+	return ContextWithHooks(ContextWithDPoP(ctx, a.DPoP), a.Hooks)
+}
+
+// assertion returns a signed RFC 7523 client assertion for a request to uri.
+func (a *ClientAuth) assertion(uri string, authStyle AuthStyle) (string, error) {
+	var (
+		signer jws.Signer
+		alg    string
+		err    error
+	)
+
+	switch authStyle {
+	case AuthStylePrivateKeyJWT:
+		signer, alg, err = jws.NewSigner(a.Algorithm, a.PrivateKey)
+	case AuthStyleClientSecretJWT:
+		signer, alg, err = jws.NewHMACSigner(a.Algorithm, []byte(a.ClientSecret))
+	default:
+		return "", fmt.Errorf("oauth2: auth style %d does not use a client assertion", authStyle)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("oauth2: cannot sign client assertion: %v", err)
+	}
+
+	jti := make([]byte, 32)
+	if _, err = rand.Read(jti); err != nil {
+		return "", fmt.Errorf("oauth2: cannot generate client assertion jti: %v", err)
+	}
+
+	aud := a.Audience
+	if aud == "" {
+		aud = uri
+	}
+
+	now := time.Now()
+
+	claims := &jws.ClaimSet{
+		Iss: a.ClientID,
+		Sub: a.ClientID,
+		Aud: aud,
+		Iat: now.Unix(),
+		Exp: now.Add(clientAssertionLifetime).Unix(),
+		Jti: base64.RawURLEncoding.EncodeToString(jti),
+	}
+
+	header := &jws.Header{
+		Algorithm: alg,
+		Typ:       "JWT",
+		KeyID:     a.PrivateKeyID,
+	}
+
+	return jws.EncodeWithSigner(header, claims, signer)
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DPoPProver creates RFC 9449 DPoP proofs. It's implemented by oauth2.DPoP.
+type DPoPProver interface {
+	// Proof returns a DPoP proof for a request with method to uri. When
+	// accessToken is not empty the proof is bound to it with the 'ath' claim.
+	Proof(method, uri, accessToken string) (string, error)
+
+	// SetNonce records the DPoP nonce provided by the server at uri.
+	SetNonce(uri, nonce string)
+}
+
+// ContextWithDPoP returns a copy of ctx whose HTTP client adds DPoP proofs
+// created by prover to each request. If prover is nil, ctx is returned
+// unchanged.
+func ContextWithDPoP(ctx context.Context, prover DPoPProver) context.Context {
+	if prover == nil {
+		return ctx
+	}
+
+	hc := *ContextClient(ctx)
+	hc.Transport = &DPoPTransport{Prover: prover, Base: hc.Transport}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, HTTPClient, &hc)
+}
+
+// DPoPTransport is an http.RoundTripper that adds a DPoP proof to each
+// request. If the request carries a DPoP Authorization header, the proof is
+// bound to its access token.
+//
+// Nonces provided by the server through the DPoP-Nonce header are recorded,
+// and a request rejected with the use_dpop_nonce error is retried once with
+// the new nonce when its body can be replayed.
+type DPoPTransport struct {
+	Prover DPoPProver
+
+	// Base is the base RoundTripper used to make HTTP requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+func (t *DPoPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var accessToken string
+	if scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "DPoP") {
+		accessToken = token
+	}
+
+	resp, err := t.roundTrip(req, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := resp.Header.Get("DPoP-Nonce")
+	if nonce == "" || !isUseDPoPNonceError(resp) {
+		return resp, nil
+	}
+
+	var body io.ReadCloser
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		if body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+
+	resp.Body.Close()
+
+	req2 := req.Clone(req.Context())
+	if body != nil {
+		req2.Body = body
+	}
+
+	return t.roundTrip(req2, accessToken)
+}
+
+func (t *DPoPTransport) roundTrip(req *http.Request, accessToken string) (*http.Response, error) {
+	proof, err := t.Prover.Proof(req.Method, req.URL.String(), accessToken)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("DPoP", proof)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce := resp.Header.Get("DPoP-Nonce"); nonce != "" {
+		t.Prover.SetNonce(req.URL.String(), nonce)
+	}
+
+	return resp, nil
+}
+
+// isUseDPoPNonceError reports whether resp is an authorization server error
+// response (RFC 9449 section 8) or a resource server challenge (RFC 9449
+// section 9) with the use_dpop_nonce error. The response body is preserved.
+func isUseDPoPNonceError(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		for _, challenge := range resp.Header.Values("WWW-Authenticate") {
+			if strings.Contains(challenge, "use_dpop_nonce") {
+				return true
+			}
+		}
+
+		return false
+	case http.StatusBadRequest:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+
+		var ej errorJSON
+		if json.Unmarshal(body, &ej) == nil {
+			return ej.ErrorCode == "use_dpop_nonce"
+		}
+
+		vals, err := url.ParseQuery(string(body))
+
+		return err == nil && vals.Get("error") == "use_dpop_nonce"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// The endpoint kinds, see oauth2.RequestInfo.
+const (
+	EndpointKindToken           = "token"
+	EndpointKindPushedAuth      = "par"
+	EndpointKindDeviceAuth      = "device_authorization"
+	EndpointKindBackchannelAuth = "backchannel_authentication"
+	EndpointKindRevocation      = "revocation"
+	EndpointKindIntrospection   = "introspection"
+	EndpointKindUserInfo        = "userinfo"
+	EndpointKindDiscovery       = "discovery"
+	EndpointKindJWKS            = "jwks"
+	EndpointKindSTS             = "sts"
+	EndpointKindImpersonation   = "impersonation"
+	EndpointKindMetadata        = "metadata"
+)
+
+// RequestInfo mirrors oauth2.RequestInfo.
+type RequestInfo struct {
+	Endpoint   string
+	GrantType  string
+	Start      time.Time
+	Duration   time.Duration
+	StatusCode int
+	ErrorCode  string
+	Err        error
+	Cached     bool
+}
+
+// Hooks is implemented by oauth2.Hooks adapters.
+type Hooks interface {
+	RequestStart(ctx context.Context, info *RequestInfo) context.Context
+	RequestDone(ctx context.Context, info *RequestInfo)
+}
+
+type hooksKey struct{}
+
+// ContextWithHooks returns a copy of ctx with the hooks h. If h is nil, ctx is
+// returned unchanged.
+func ContextWithHooks(ctx context.Context, h Hooks) context.Context {
+	if h == nil {
+		return ctx
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, hooksKey{}, h)
+}
+
+// ContextHooks returns the hooks of ctx, or nil if it has none.
+func ContextHooks(ctx context.Context) Hooks {
+	if ctx == nil {
+		return nil
+	}
+
+	h, _ := ctx.Value(hooksKey{}).(Hooks)
+
+	return h
+}
+
+// Observe calls do, which makes a single HTTP request to the endpoint with the
+// client of the context it's passed, between calls to the hooks of ctx. The
+// status code of the response is recorded by wrapping the client's transport.
+// If ctx has no hooks, do is called with ctx.
+func Observe[T any](ctx context.Context, endpoint, grantType string, do func(context.Context) (T, error)) (result T, err error) {
+	h := ContextHooks(ctx)
+	if h == nil {
+		return do(ctx)
+	}
+
+	info := &RequestInfo{
+		Endpoint:  endpoint,
+		GrantType: grantType,
+		Start:     time.Now(),
+	}
+
+	ctx = h.RequestStart(ctx, info)
+
+	hc := *ContextClient(ctx)
+	hc.Transport = &statusTransport{base: hc.Transport, info: info}
+
+	result, err = do(context.WithValue(ctx, HTTPClient, &hc))
+
+	info.Duration = time.Since(info.Start)
+	info.Err = err
+	info.ErrorCode = errorCode(err)
+
+	h.RequestDone(ctx, info)
+
+	return result, err
+}
+
+// ObserveCached calls the hooks of ctx for a token of the endpoint returned
+// from a cache without an HTTP request.
+func ObserveCached(ctx context.Context, endpoint, grantType string) {
+	h := ContextHooks(ctx)
+	if h == nil {
+		return
+	}
+
+	info := &RequestInfo{
+		Endpoint:  endpoint,
+		GrantType: grantType,
+		Start:     time.Now(),
+		Cached:    true,
+	}
+
+	h.RequestDone(h.RequestStart(ctx, info), info)
+}
+
+// statusTransport records the status code of responses to info.
+type statusTransport struct {
+	base http.RoundTripper
+	info *RequestInfo
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if resp != nil {
+		t.info.StatusCode = resp.StatusCode
+	}
+
+	return resp, err
+}
+
+// errorCode returns RFC 6749's 'error' parameter of err, if any.
+func errorCode(err error) string {
+	var cErr interface{ GetErrorCode() string }
+	if errors.As(err, &cErr) {
+		return cErr.GetErrorCode()
+	}
+
+	return ""
+}
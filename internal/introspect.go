@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// IntrospectToken performs an RFC 7662 token introspection request and returns
+// the raw JSON introspection response.
+//
+// Client authentication is handled similar to the token endpoint, including
+// the auth style probing. See https://datatracker.ietf.org/doc/html/rfc7662#section-2.1.
+func IntrospectToken(ctx context.Context, auth *ClientAuth, introspectionURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) ([]byte, error) {
+	ctx = auth.context(ctx)
+
+	needsAuthStyleProbe := authStyle == 0
+	if needsAuthStyleProbe {
+		if style, ok := styleCache.lookupAuthStyle(introspectionURL); ok {
+			authStyle = style
+			needsAuthStyleProbe = false
+		} else {
+			authStyle = AuthStyleInHeader // the first way we'll try
+		}
+	}
+
+	newRequest := func() (*http.Request, error) {
+		return newPOSTRequest(introspectionURL, auth, v, authStyle)
+	}
+
+	body, err := roundTripWithRetry(ctx, auth, true, EndpointKindIntrospection, "", newRequest, doIntrospectRoundTrip)
+	if err != nil && needsAuthStyleProbe {
+		authStyle = AuthStyleInParams // the second way we'll try
+		body, err = roundTripWithRetry(ctx, auth, true, EndpointKindIntrospection, "", newRequest, doIntrospectRoundTrip)
+	}
+
+	if needsAuthStyleProbe && err == nil {
+		styleCache.setAuthStyle(introspectionURL, authStyle)
+	}
+
+	return body, err
+}
+
+func doIntrospectRoundTrip(ctx context.Context, req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+
+	r, err := ContextClient(ctx).Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot introspect token: %v", err)
+	}
+
+	introspectionError := &IntrospectionError{
+		Response: r,
+		Body:     body,
+		// attempt to populate error detail below
+	}
+
+	var ej errorJSON
+
+	content, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch content {
+	case "application/x-www-form-urlencoded", "text/plain":
+		vals, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, introspectionError
+		}
+
+		ej.ErrorCode = vals.Get("error")
+		ej.ErrorDescription = vals.Get("error_description")
+		ej.ErrorURI = vals.Get("error_uri")
+	default:
+		if err = json.Unmarshal(body, &ej); err != nil {
+			if r.StatusCode < 200 || r.StatusCode > 299 {
+				return nil, introspectionError
+			}
+			return nil, fmt.Errorf("oauth2: cannot parse introspection response: %v", err)
+		}
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 || ej.ErrorCode != "" {
+		introspectionError.ErrorCode = ej.ErrorCode
+		introspectionError.ErrorDescription = ej.ErrorDescription
+		introspectionError.ErrorURI = ej.ErrorURI
+
+		return nil, introspectionError
+	}
+
+	return body, nil
+}
+
+// IntrospectionError mirrors oauth2.BaseError.
+type IntrospectionError struct {
+	Response         *http.Response
+	Body             []byte
+	ErrorCode        string
+	ErrorDescription string
+	ErrorURI         string
+}
+
+// Temporary reports whether the request may be retried.
+func (r *IntrospectionError) Temporary() bool {
+	return IsTemporary(statusCode(r.Response), r.ErrorCode)
+}
+
+func (r *IntrospectionError) GetResponse() *http.Response {
+	return r.Response
+}
+
+func (r *IntrospectionError) GetErrorCode() string {
+	return r.ErrorCode
+}
+
+func (r *IntrospectionError) Error() string {
+	if r.ErrorCode != "" {
+		s := fmt.Sprintf("oauth2: %q", r.ErrorCode)
+		if r.ErrorDescription != "" {
+			s += fmt.Sprintf(" %q", r.ErrorDescription)
+		}
+		if r.ErrorURI != "" {
+			s += fmt.Sprintf(" %q", r.ErrorURI)
+		}
+		return s
+	}
+	return fmt.Sprintf("oauth2: cannot introspect token: %v", r.Response.Status)
+}
@@ -0,0 +1,526 @@
+// Package jwe provides a partial implementation of JSON Web Encryption
+// encoding and decoding in compact serialization.
+//
+// See RFC 7516 and RFC 7518.
+package jwe
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// Header represents the protected header of a JWE.
+type Header struct {
+	// The key management algorithm.
+	Algorithm string `json:"alg"`
+
+	// The content encryption algorithm.
+	Encryption string `json:"enc"`
+
+	// The optional hint of which key is being used.
+	KeyID string `json:"kid,omitempty"`
+
+	// Represents the token type.
+	Typ string `json:"typ,omitempty"`
+
+	// The optional content type of the plaintext, such as JWT for nested tokens.
+	ContentType string `json:"cty,omitempty"`
+
+	// The ephemeral public key used with ECDH-ES key agreement.
+	EphemeralPublicKey json.RawMessage `json:"epk,omitempty"`
+
+	// The optional agreement PartyUInfo and PartyVInfo used with ECDH-ES.
+	AgreementPartyUInfo string `json:"apu,omitempty"`
+	AgreementPartyVInfo string `json:"apv,omitempty"`
+
+	// The optional compression algorithm, which is not supported.
+	Compression string `json:"zip,omitempty"`
+}
+
+// IsEncrypted reports whether token is in JWE rather than JWS compact
+// serialization.
+func IsEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// Decrypt decrypts a JWE in compact serialization with key and returns the
+// plaintext and the protected header.
+//
+// The key may be an *rsa.PrivateKey or any crypto.Decrypter with an RSA public
+// key for the RSA-OAEP and RSA-OAEP-256 algorithms, or an *ecdsa.PrivateKey or
+// *ecdh.PrivateKey for the ECDH-ES family of algorithms.
+func Decrypt(token string, key crypto.PrivateKey) ([]byte, *Header, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, nil, errors.New("jwe: invalid token received, token must have 5 parts")
+	}
+
+	decoded := make([][]byte, 5)
+	for i, part := range parts {
+		var err error
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, nil, fmt.Errorf("jwe: invalid token received: %v", err)
+		}
+	}
+
+	header := &Header{}
+	if err := json.Unmarshal(decoded[0], header); err != nil {
+		return nil, nil, fmt.Errorf("jwe: invalid header: %v", err)
+	}
+
+	if header.Compression != "" {
+		return nil, nil, fmt.Errorf("jwe: unsupported compression algorithm %q", header.Compression)
+	}
+
+	size, err := keySize(header.Encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cek, err := decryptKey(header, decoded[1], key, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(cek) != size {
+		return nil, nil, errors.New("jwe: invalid content encryption key length")
+	}
+
+	plaintext, err := decryptContent(header.Encryption, cek, decoded[2], decoded[3], decoded[4], []byte(parts[0]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, header, nil
+}
+
+// Encrypt encrypts plaintext to key and returns the JWE in compact
+// serialization. The header's Algorithm and Encryption must be set, and its
+// other members are included in the protected header as is.
+//
+// The key must be an *rsa.PublicKey for the RSA-OAEP and RSA-OAEP-256
+// algorithms, or an *ecdsa.PublicKey or *ecdh.PublicKey for the ECDH-ES
+// family of algorithms.
+func Encrypt(plaintext []byte, header *Header, key crypto.PublicKey) (string, error) {
+	h := *header
+
+	size, err := keySize(h.Encryption)
+	if err != nil {
+		return "", err
+	}
+
+	var cek, encryptedKey []byte
+
+	switch h.Algorithm {
+	case "RSA-OAEP", "RSA-OAEP-256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return "", fmt.Errorf("jwe: algorithm %s requires an RSA key but got %T", h.Algorithm, key)
+		}
+		hh := sha256.New()
+		if h.Algorithm == "RSA-OAEP" {
+			hh = sha1.New()
+		}
+		cek = make([]byte, size)
+		if _, err = rand.Read(cek); err != nil {
+			return "", err
+		}
+		if encryptedKey, err = rsa.EncryptOAEP(hh, rand.Reader, pub, cek, nil); err != nil {
+			return "", fmt.Errorf("jwe: cannot encrypt content encryption key: %v", err)
+		}
+	case "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW":
+		pub, err := ecdhPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		ephemeral, err := pub.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		z, err := ephemeral.ECDH(pub)
+		if err != nil {
+			return "", fmt.Errorf("jwe: key agreement failed: %v", err)
+		}
+		if h.EphemeralPublicKey, err = ephemeralJWK(ephemeral.PublicKey()); err != nil {
+			return "", err
+		}
+		apu, _ := base64.RawURLEncoding.DecodeString(h.AgreementPartyUInfo)
+		apv, _ := base64.RawURLEncoding.DecodeString(h.AgreementPartyVInfo)
+		if h.Algorithm == "ECDH-ES" {
+			cek = concatKDF(z, h.Encryption, size, apu, apv)
+			break
+		}
+		cek = make([]byte, size)
+		if _, err = rand.Read(cek); err != nil {
+			return "", err
+		}
+		kek := concatKDF(z, h.Algorithm, keyWrapSize(h.Algorithm), apu, apv)
+		if encryptedKey, err = aesKeyWrap(kek, cek); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("jwe: unsupported key management algorithm %q", h.Algorithm)
+	}
+
+	b, err := json.Marshal(&h)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(b)
+
+	iv, ciphertext, tag, err := encryptContent(h.Encryption, cek, plaintext, []byte(protected))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+func ephemeralJWK(pub *ecdh.PublicKey) (json.RawMessage, error) {
+	var jwk map[string]any
+
+	if pub.Curve() == ecdh.X25519() {
+		jwk = map[string]any{
+			"kty": "OKP",
+			"crv": "X25519",
+			"x":   base64.RawURLEncoding.EncodeToString(pub.Bytes()),
+		}
+	} else {
+		// The uncompressed point encoding is 0x04 || X || Y.
+		point := pub.Bytes()[1:]
+		crv := map[int]string{64: "P-256", 96: "P-384", 132: "P-521"}[len(point)]
+		jwk = map[string]any{
+			"kty": "EC",
+			"crv": crv,
+			"x":   base64.RawURLEncoding.EncodeToString(point[:len(point)/2]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[len(point)/2:]),
+		}
+	}
+
+	return json.Marshal(jwk)
+}
+
+// aesKeyWrap implements the RFC 3394 AES key wrap algorithm.
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("jwe: invalid key length to wrap")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	a := make([]byte, 8)
+	copy(a, keyWrapIV)
+	r := make([]byte, len(key))
+	copy(r, key)
+
+	b := make([]byte, 16)
+	for j := 0; j <= 5; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, a)
+			copy(b[8:], r[(i-1)*8:i*8])
+			block.Encrypt(b, b)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^uint64(n*j+i))
+			copy(r[(i-1)*8:i*8], b[8:])
+		}
+	}
+
+	return append(a, r...), nil
+}
+
+func encryptContent(enc string, cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	switch enc {
+	case "A128GCM", "A192GCM", "A256GCM":
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		iv = make([]byte, gcm.NonceSize())
+		if _, err = rand.Read(iv); err != nil {
+			return nil, nil, nil, err
+		}
+		sealed := gcm.Seal(nil, iv, plaintext, aad)
+		split := len(sealed) - gcm.Overhead()
+		return iv, sealed[:split], sealed[split:], nil
+	default:
+		macKey, encKey := cek[:len(cek)/2], cek[len(cek)/2:]
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		iv = make([]byte, block.BlockSize())
+		if _, err = rand.Read(iv); err != nil {
+			return nil, nil, nil, err
+		}
+		pad := block.BlockSize() - len(plaintext)%block.BlockSize()
+		padded := append(append([]byte{}, plaintext...), make([]byte, pad)...)
+		for i := len(plaintext); i < len(padded); i++ {
+			padded[i] = byte(pad)
+		}
+		ciphertext = make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+		return iv, ciphertext, cbcHMACTag(cbcHash(enc), macKey, aad, iv, ciphertext), nil
+	}
+}
+
+// keySize returns the content encryption key size in bytes for enc.
+func keySize(enc string) (int, error) {
+	switch enc {
+	case "A128GCM":
+		return 16, nil
+	case "A192GCM":
+		return 24, nil
+	case "A256GCM", "A128CBC-HS256":
+		return 32, nil
+	case "A192CBC-HS384":
+		return 48, nil
+	case "A256CBC-HS512":
+		return 64, nil
+	default:
+		return 0, fmt.Errorf("jwe: unsupported content encryption algorithm %q", enc)
+	}
+}
+
+func decryptKey(header *Header, encryptedKey []byte, key crypto.PrivateKey, size int) ([]byte, error) {
+	switch header.Algorithm {
+	case "RSA-OAEP", "RSA-OAEP-256":
+		d, ok := key.(crypto.Decrypter)
+		if !ok {
+			return nil, fmt.Errorf("jwe: algorithm %s requires an RSA key but got %T", header.Algorithm, key)
+		}
+		if _, ok = d.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwe: algorithm %s requires an RSA key but got %T", header.Algorithm, d.Public())
+		}
+		h := crypto.SHA1
+		if header.Algorithm == "RSA-OAEP-256" {
+			h = crypto.SHA256
+		}
+		cek, err := d.Decrypt(rand.Reader, encryptedKey, &rsa.OAEPOptions{Hash: h})
+		if err != nil {
+			return nil, fmt.Errorf("jwe: cannot decrypt content encryption key: %v", err)
+		}
+		return cek, nil
+	case "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW":
+		priv, err := ecdhPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		epk, err := jws.ParseJWK(header.EphemeralPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("jwe: invalid ephemeral public key: %v", err)
+		}
+		pub, err := ecdhPublicKey(epk.Key)
+		if err != nil {
+			return nil, err
+		}
+		z, err := priv.ECDH(pub)
+		if err != nil {
+			return nil, fmt.Errorf("jwe: key agreement failed: %v", err)
+		}
+		apu, err := base64.RawURLEncoding.DecodeString(header.AgreementPartyUInfo)
+		if err != nil {
+			return nil, fmt.Errorf("jwe: invalid apu: %v", err)
+		}
+		apv, err := base64.RawURLEncoding.DecodeString(header.AgreementPartyVInfo)
+		if err != nil {
+			return nil, fmt.Errorf("jwe: invalid apv: %v", err)
+		}
+		if header.Algorithm == "ECDH-ES" {
+			if len(encryptedKey) != 0 {
+				return nil, errors.New("jwe: unexpected encrypted key with direct key agreement")
+			}
+			return concatKDF(z, header.Encryption, size, apu, apv), nil
+		}
+		kek := concatKDF(z, header.Algorithm, keyWrapSize(header.Algorithm), apu, apv)
+		return aesKeyUnwrap(kek, encryptedKey)
+	default:
+		return nil, fmt.Errorf("jwe: unsupported key management algorithm %q", header.Algorithm)
+	}
+}
+
+func keyWrapSize(alg string) int {
+	switch {
+	case strings.HasSuffix(alg, "A128KW"):
+		return 16
+	case strings.HasSuffix(alg, "A192KW"):
+		return 24
+	default:
+		return 32
+	}
+}
+
+func ecdhPrivateKey(key crypto.PrivateKey) (*ecdh.PrivateKey, error) {
+	switch k := key.(type) {
+	case *ecdh.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k.ECDH()
+	default:
+		return nil, fmt.Errorf("jwe: ECDH-ES requires an ECDH or ECDSA private key but got %T", key)
+	}
+}
+
+func ecdhPublicKey(key crypto.PublicKey) (*ecdh.PublicKey, error) {
+	switch k := key.(type) {
+	case *ecdh.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		return k.ECDH()
+	default:
+		return nil, fmt.Errorf("jwe: ECDH-ES requires an ECDH or ECDSA public key but got %T", key)
+	}
+}
+
+// concatKDF implements the Concat KDF from NIST SP 800-56A as profiled by
+// RFC 7518 section 4.6.2, deriving size bytes of key material.
+func concatKDF(z []byte, alg string, size int, apu, apv []byte) []byte {
+	var info []byte
+	for _, v := range [][]byte{[]byte(alg), apu, apv} {
+		info = binary.BigEndian.AppendUint32(info, uint32(len(v)))
+		info = append(info, v...)
+	}
+	info = binary.BigEndian.AppendUint32(info, uint32(size*8))
+
+	var out []byte
+	for counter := uint32(1); len(out) < size; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(z)
+		h.Write(info)
+		out = h.Sum(out)
+	}
+
+	return out[:size]
+}
+
+var keyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// aesKeyUnwrap implements the RFC 3394 AES key unwrap algorithm.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("jwe: invalid wrapped key length")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	r := make([]byte, len(wrapped)-8)
+	copy(r, wrapped[8:])
+
+	b := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], r[(i-1)*8:i*8])
+			block.Decrypt(b, b)
+			copy(a, b[:8])
+			copy(r[(i-1)*8:i*8], b[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errors.New("jwe: key unwrap integrity check failed")
+	}
+
+	return r, nil
+}
+
+func decryptContent(enc string, cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	switch enc {
+	case "A128GCM", "A192GCM", "A256GCM":
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != gcm.NonceSize() {
+			return nil, errors.New("jwe: invalid initialization vector length")
+		}
+		plaintext, err := gcm.Open(nil, iv, append(append([]byte{}, ciphertext...), tag...), aad)
+		if err != nil {
+			return nil, errors.New("jwe: decryption failed")
+		}
+		return plaintext, nil
+	default:
+		macKey, encKey := cek[:len(cek)/2], cek[len(cek)/2:]
+		if !hmac.Equal(tag, cbcHMACTag(cbcHash(enc), macKey, aad, iv, ciphertext)) {
+			return nil, errors.New("jwe: decryption failed")
+		}
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != block.BlockSize() || len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+			return nil, errors.New("jwe: decryption failed")
+		}
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+		pad := int(plaintext[len(plaintext)-1])
+		if pad == 0 || pad > block.BlockSize() {
+			return nil, errors.New("jwe: decryption failed")
+		}
+		return plaintext[:len(plaintext)-pad], nil
+	}
+}
+
+func cbcHash(enc string) func() hash.Hash {
+	switch enc {
+	case "A192CBC-HS384":
+		return sha512.New384
+	case "A256CBC-HS512":
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+// cbcHMACTag computes the authentication tag of RFC 7518 section 5.2.2.1.
+func cbcHMACTag(h func() hash.Hash, macKey, aad, iv, ciphertext []byte) []byte {
+	mac := hmac.New(h, macKey)
+	mac.Write(aad)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(len(aad))*8))
+	return mac.Sum(nil)[:len(macKey)]
+}
@@ -0,0 +1,91 @@
+package jwe
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		alg, enc string
+		pub      crypto.PublicKey
+		priv     crypto.PrivateKey
+	}{
+		{"RSA-OAEP", "A128CBC-HS256", &rsaKey.PublicKey, rsaKey},
+		{"RSA-OAEP-256", "A256GCM", &rsaKey.PublicKey, rsaKey},
+		{"ECDH-ES", "A128GCM", &ecKey.PublicKey, ecKey},
+		{"ECDH-ES+A128KW", "A256CBC-HS512", &ecKey.PublicKey, ecKey},
+		{"ECDH-ES+A256KW", "A192GCM", xKey.PublicKey(), xKey},
+	}
+	for _, tc := range cases {
+		t.Run(tc.alg+"/"+tc.enc, func(t *testing.T) {
+			plaintext := []byte(`{"sub":"123"}`)
+			token, err := Encrypt(plaintext, &Header{Algorithm: tc.alg, Encryption: tc.enc, ContentType: "JWT"}, tc.pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(token) {
+				t.Errorf("IsEncrypted(%q) = false; want true", token)
+			}
+			got, header, err := Decrypt(token, tc.priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(plaintext) {
+				t.Errorf("Decrypt = %q; want %q", got, plaintext)
+			}
+			if header.ContentType != "JWT" {
+				t.Errorf("header.ContentType = %q; want JWT", header.ContentType)
+			}
+			if _, _, err = Decrypt(token[:len(token)-4]+"AAAA", tc.priv); err == nil {
+				t.Error("Decrypt with tampered tag = nil error; want error")
+			}
+		})
+	}
+}
+
+func TestAESKeyWrap(t *testing.T) {
+	// RFC 3394 section 4.1 test vector.
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+	want := "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"
+
+	wrapped, err := aesKeyWrap(kek, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(wrapped); got != hexLower(want) {
+		t.Errorf("aesKeyWrap = %s; want %s", got, want)
+	}
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(unwrapped) != hex.EncodeToString(key) {
+		t.Errorf("aesKeyUnwrap = %x; want %x", unwrapped, key)
+	}
+}
+
+func hexLower(s string) string {
+	b, _ := hex.DecodeString(s)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,46 @@
+package jws
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultClockSkew is the allowed difference between the clocks of the client
+// and the issuer when validating the times of the claims, see
+// idtoken.DefaultClockSkew.
+const DefaultClockSkew = time.Minute
+
+// NumericDate is an RFC 7519 NumericDate, the number of seconds since the
+// epoch, which may be an integer or have a fraction.
+type NumericDate float64
+
+// Time returns the time of d, or the zero time if d is zero.
+func (d NumericDate) Time() time.Time {
+	if d == 0 {
+		return time.Time{}
+	}
+
+	sec := int64(d)
+
+	return time.Unix(sec, int64((float64(d)-float64(sec))*float64(time.Second)))
+}
+
+// ParseAudience parses the RFC 7519 'aud' claim, which is either a single
+// string or an array of strings.
+func ParseAudience(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err != nil {
+		return nil, err
+	}
+
+	return multiple, nil
+}
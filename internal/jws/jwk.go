@@ -0,0 +1,252 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// PublicJWK returns the JSON Web Key representation of the public key as
+// described in RFC 7517, containing only the members required by RFC 7638.
+func PublicJWK(key crypto.PublicKey) (map[string]any, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]any{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		x, y := make([]byte, size), make([]byte, size)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+
+		return map[string]any{
+			"kty": "EC",
+			"crv": k.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(x),
+			"y":   base64.RawURLEncoding.EncodeToString(y),
+		}, nil
+	case ed25519.PublicKey:
+		return map[string]any{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return nil, fmt.Errorf("jws: unsupported key type %T", key)
+	}
+}
+
+// Thumbprint returns the base64url encoded RFC 7638 SHA-256 thumbprint of a
+// JWK returned by PublicJWK.
+func Thumbprint(jwk map[string]any) (string, error) {
+	// json.Marshal sorts map keys which gives the lexicographic member order
+	// RFC 7638 requires, and PublicJWK only includes the required members.
+	b, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// JWK is a parsed public JSON Web Key.
+type JWK struct {
+	// KeyID is the 'kid' member.
+	KeyID string
+	// Algorithm is the optional 'alg' member.
+	Algorithm string
+	// Use is the optional 'use' member, either "sig" or "enc".
+	Use string
+	// Key is the *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or
+	// *ecdh.PublicKey (for X25519).
+	Key crypto.PublicKey
+	// Certificates is the optional 'x5c' certificate chain, starting with the
+	// certificate containing Key.
+	Certificates []*x509.Certificate
+}
+
+type rawJWK struct {
+	KeyType   string   `json:"kty"`
+	KeyID     string   `json:"kid"`
+	Algorithm string   `json:"alg"`
+	Use       string   `json:"use"`
+	Curve     string   `json:"crv"`
+	N         string   `json:"n"`
+	E         string   `json:"e"`
+	X         string   `json:"x"`
+	Y         string   `json:"y"`
+	X5C       []string `json:"x5c"`
+}
+
+// ParseJWK parses a single public JSON Web Key. RSA, EC (P-256, P-384, P-521)
+// and OKP (Ed25519, X25519) keys are supported. When the key has an 'x5c'
+// certificate chain, each certificate must be signed by the next one and the
+// first certificate must contain the key.
+func ParseJWK(data []byte) (*JWK, error) {
+	var raw rawJWK
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("jws: invalid JWK: %v", err)
+	}
+
+	jwk := &JWK{
+		KeyID:     raw.KeyID,
+		Algorithm: raw.Algorithm,
+		Use:       raw.Use,
+	}
+
+	var err error
+
+	switch raw.KeyType {
+	case "RSA":
+		jwk.Key, err = parseRSAJWK(&raw)
+	case "EC":
+		jwk.Key, err = parseECJWK(&raw)
+	case "OKP":
+		jwk.Key, err = parseOKPJWK(&raw)
+	default:
+		err = fmt.Errorf("unsupported key type %q", raw.KeyType)
+	}
+
+	if err == nil && len(raw.X5C) != 0 {
+		jwk.Certificates, err = parseX5C(raw.X5C, jwk.Key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("jws: invalid JWK %q: %v", raw.KeyID, err)
+	}
+
+	return jwk, nil
+}
+
+// parseX5C parses the 'x5c' certificate chain and validates that it's linked
+// and that the first certificate contains key.
+func parseX5C(x5c []string, key crypto.PublicKey) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, len(x5c))
+
+	for i, value := range x5c {
+		// The 'x5c' values are base64 encoded, not base64url encoded.
+		der, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x5c certificate %d: %v", i, err)
+		}
+
+		if certs[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("invalid x5c certificate %d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < len(certs)-1; i++ {
+		if err := certs[i].CheckSignatureFrom(certs[i+1]); err != nil {
+			return nil, fmt.Errorf("invalid x5c certificate chain: %v", err)
+		}
+	}
+
+	type equaler interface {
+		Equal(x crypto.PublicKey) bool
+	}
+
+	if k, ok := key.(equaler); !ok || !k.Equal(certs[0].PublicKey) {
+		return nil, errors.New("x5c certificate does not match the key")
+	}
+
+	return certs, nil
+}
+
+func parseRSAJWK(raw *rawJWK) (crypto.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(raw.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(raw.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func parseECJWK(raw *rawJWK) (crypto.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch raw.Curve {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", raw.Curve)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+
+	x, err := base64.RawURLEncoding.DecodeString(raw.X)
+	if err != nil || len(x) != size {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(raw.Y)
+	if err != nil || len(y) != size {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	// Validate the point is on the curve by parsing it as an ECDH key.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err = ecdhCurve(raw.Curve).NewPublicKey(point); err != nil {
+		return nil, errors.New("invalid point")
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+func ecdhCurve(crv string) ecdh.Curve {
+	switch crv {
+	case "P-384":
+		return ecdh.P384()
+	case "P-521":
+		return ecdh.P521()
+	default:
+		return ecdh.P256()
+	}
+}
+
+func parseOKPJWK(raw *rawJWK) (crypto.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(raw.X)
+	if err != nil {
+		return nil, errors.New("invalid x coordinate")
+	}
+
+	switch raw.Curve {
+	case "Ed25519":
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid x coordinate")
+		}
+		return ed25519.PublicKey(x), nil
+	case "X25519":
+		return ecdh.X25519().NewPublicKey(x)
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", raw.Curve)
+	}
+}
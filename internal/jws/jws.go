@@ -32,6 +32,7 @@ type ClaimSet struct {
 	Exp   int64  `json:"exp"`             // the expiration time of the assertion (seconds since Unix epoch)
 	Iat   int64  `json:"iat"`             // the time the assertion was issued (seconds since Unix epoch)
 	Typ   string `json:"typ,omitempty"`   // token type (Optional).
+	Jti   string `json:"jti,omitempty"`   // unique identifier of the assertion (Optional).
 
 	// Email for which the application is requesting delegated access (Optional).
 	Sub string `json:"sub,omitempty"`
@@ -97,6 +98,9 @@ type Header struct {
 
 	// The optional hint of which key is being used.
 	KeyID string `json:"kid,omitempty"`
+
+	// The optional content type of the payload, such as JWT for nested tokens.
+	ContentType string `json:"cty,omitempty"`
 }
 
 func (h *Header) encode() (string, error) {
@@ -145,6 +149,25 @@ func EncodeWithSigner(header *Header, c *ClaimSet, sg Signer) (string, error) {
 	return fmt.Sprintf("%s.%s", ss, base64.RawURLEncoding.EncodeToString(sig)), nil
 }
 
+// EncodeClaimsWithSigner encodes an arbitrary JSON header and claims with the
+// provided signer. Unlike EncodeWithSigner, no claims are added or validated.
+func EncodeClaimsWithSigner(header, claims any, sg Signer) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	ss := fmt.Sprintf("%s.%s", base64.RawURLEncoding.EncodeToString(h), base64.RawURLEncoding.EncodeToString(b))
+	sig, err := sg([]byte(ss))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s", ss, base64.RawURLEncoding.EncodeToString(sig)), nil
+}
+
 // Encode encodes a signed JWS with provided header and claim set.
 // This invokes EncodeWithSigner using crypto/rsa.SignPKCS1v15 with the given RSA private key.
 func Encode(header *Header, c *ClaimSet, key *rsa.PrivateKey) (string, error) {
@@ -5,8 +5,15 @@
 package jws
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
@@ -40,6 +47,119 @@ func TestSignAndVerify(t *testing.T) {
 	}
 }
 
+func TestNewSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyRSA := func(sig, digest []byte) error {
+		return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest, sig)
+	}
+
+	cases := []struct {
+		desc    string
+		alg     string
+		key     crypto.Signer
+		wantAlg string
+		verify  func(sig, digest []byte) error
+	}{
+		{
+			desc:    "rsa default",
+			key:     rsaKey,
+			wantAlg: RS256,
+			verify:  verifyRSA,
+		}, {
+			desc:    "rsa pss",
+			alg:     PS256,
+			key:     rsaKey,
+			wantAlg: PS256,
+			verify: func(sig, digest []byte) error {
+				return rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, digest, sig, nil)
+			},
+		}, {
+			desc:    "ecdsa",
+			key:     ecKey,
+			wantAlg: ES256,
+			verify: func(sig, digest []byte) error {
+				if len(sig) != 64 {
+					t.Fatalf("len(sig) = %d; want 64", len(sig))
+				}
+				r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+				if !ecdsa.Verify(&ecKey.PublicKey, digest, r, s) {
+					t.Error("ecdsa.Verify = false; want true")
+				}
+				return nil
+			},
+		}, {
+			desc:    "ed25519",
+			key:     edKey,
+			wantAlg: EdDSA,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sg, alg, err := NewSigner(tc.alg, tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if alg != tc.wantAlg {
+				t.Errorf("alg = %q; want %q", alg, tc.wantAlg)
+			}
+			token, err := EncodeWithSigner(&Header{Algorithm: alg, Typ: "JWT"}, &ClaimSet{Iss: "client", Jti: "id"}, sg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, claims, sig, _ := parseToken(token)
+			rawSig, err := base64.RawURLEncoding.DecodeString(sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.verify == nil {
+				if !ed25519.Verify(edKey.Public().(ed25519.PublicKey), []byte(header+"."+claims), rawSig) {
+					t.Error("ed25519.Verify = false; want true")
+				}
+				return
+			}
+			digest := sha256.Sum256([]byte(header + "." + claims))
+			if err := tc.verify(rawSig, digest[:]); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, _, err := NewSigner(ES256, rsaKey); err == nil {
+		t.Error("NewSigner(ES256, rsaKey) = nil error; want error")
+	}
+}
+
+func TestNewHMACSigner(t *testing.T) {
+	cases := []struct {
+		alg  string
+		size int
+	}{
+		{HS256, 32},
+		{HS384, 48},
+		{HS512, 64},
+	}
+	for _, tc := range cases {
+		if _, _, err := NewHMACSigner(tc.alg, make([]byte, tc.size)); err != nil {
+			t.Errorf("NewHMACSigner(%s, %d bytes) = %v; want nil error", tc.alg, tc.size, err)
+		}
+		if _, _, err := NewHMACSigner(tc.alg, make([]byte, tc.size-1)); err == nil {
+			t.Errorf("NewHMACSigner(%s, %d bytes) = nil error; want error", tc.alg, tc.size-1)
+		}
+	}
+}
+
 func TestVerifyFailsOnMalformedClaim(t *testing.T) {
 	cases := []struct {
 		desc  string
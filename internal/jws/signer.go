@@ -0,0 +1,220 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// JWS algorithm identifiers as registered in RFC 7518 and RFC 8037.
+const (
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	PS256 = "PS256"
+	PS384 = "PS384"
+	PS512 = "PS512"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+	EdDSA = "EdDSA"
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+)
+
+// AlgorithmForKey returns the default JWS algorithm for the public key. RSA keys
+// default to RS256, ECDSA keys to the algorithm matching their curve, and Ed25519
+// keys to EdDSA.
+func AlgorithmForKey(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return RS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ES256, nil
+		case elliptic.P384():
+			return ES384, nil
+		case elliptic.P521():
+			return ES512, nil
+		}
+		return "", fmt.Errorf("jws: unsupported elliptic curve %q", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	default:
+		return "", fmt.Errorf("jws: unsupported key type %T", key)
+	}
+}
+
+// NewSigner returns a Signer that signs data with key using the JWS algorithm
+// alg. If alg is empty the algorithm is derived from the key using
+// AlgorithmForKey. The returned algorithm should be used as the header's
+// Algorithm.
+//
+// The key may be backed by hardware or a remote service such as a KMS, as only
+// its crypto.Signer implementation is used.
+func NewSigner(alg string, key crypto.Signer) (Signer, string, error) {
+	if key == nil {
+		return nil, "", fmt.Errorf("jws: no signing key provided")
+	}
+
+	var err error
+
+	if alg == "" {
+		if alg, err = AlgorithmForKey(key.Public()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	switch alg {
+	case RS256, RS384, RS512:
+		if _, ok := key.Public().(*rsa.PublicKey); !ok {
+			return nil, "", fmt.Errorf("jws: algorithm %s requires an RSA key but got %T", alg, key.Public())
+		}
+
+		h := hashForAlgorithm(alg)
+
+		return func(data []byte) ([]byte, error) {
+			return key.Sign(rand.Reader, digest(h, data), h)
+		}, alg, nil
+	case PS256, PS384, PS512:
+		if _, ok := key.Public().(*rsa.PublicKey); !ok {
+			return nil, "", fmt.Errorf("jws: algorithm %s requires an RSA key but got %T", alg, key.Public())
+		}
+
+		h := hashForAlgorithm(alg)
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+
+		return func(data []byte) ([]byte, error) {
+			return key.Sign(rand.Reader, digest(h, data), opts)
+		}, alg, nil
+	case ES256, ES384, ES512:
+		pub, ok := key.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, "", fmt.Errorf("jws: algorithm %s requires an ECDSA key but got %T", alg, key.Public())
+		}
+
+		if expected, _ := AlgorithmForKey(pub); expected != alg {
+			return nil, "", fmt.Errorf("jws: algorithm %s does not match the elliptic curve %q", alg, pub.Curve.Params().Name)
+		}
+
+		h := hashForAlgorithm(alg)
+		size := (pub.Curve.Params().BitSize + 7) / 8
+
+		return func(data []byte) ([]byte, error) {
+			der, err := key.Sign(rand.Reader, digest(h, data), h)
+			if err != nil {
+				return nil, err
+			}
+
+			return ecdsaDERToRaw(der, size)
+		}, alg, nil
+	case EdDSA:
+		if _, ok := key.Public().(ed25519.PublicKey); !ok {
+			return nil, "", fmt.Errorf("jws: algorithm %s requires an Ed25519 key but got %T", alg, key.Public())
+		}
+
+		return func(data []byte) ([]byte, error) {
+			return key.Sign(rand.Reader, data, crypto.Hash(0))
+		}, alg, nil
+	default:
+		return nil, "", fmt.Errorf("jws: unsupported signing algorithm %q", alg)
+	}
+}
+
+func hashForAlgorithm(alg string) crypto.Hash {
+	switch alg[2:] {
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digest(h crypto.Hash, data []byte) []byte {
+	hh := h.New()
+	hh.Write(data)
+	return hh.Sum(nil)
+}
+
+// ecdsaDERToRaw converts an ASN.1 DER encoded ECDSA signature, as returned by
+// crypto.Signer implementations, into the fixed size R || S form used by JWS.
+func ecdsaDERToRaw(der []byte, size int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("jws: invalid ECDSA signature: %v", err)
+	}
+
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+
+	return raw, nil
+}
+
+// NewHMACSigner returns a Signer that signs data with the shared secret using
+// the HMAC based JWS algorithm alg. If alg is empty HS256 is used.
+func NewHMACSigner(alg string, secret []byte) (Signer, string, error) {
+	var h func() hash.Hash
+
+	switch alg {
+	case HS256, "":
+		alg, h = HS256, sha256.New
+	case HS384:
+		h = sha512.New384
+	case HS512:
+		h = sha512.New
+	default:
+		return nil, "", fmt.Errorf("jws: unsupported HMAC algorithm %q", alg)
+	}
+
+	// The secret must be at least as long as the hash output, as required by
+	// RFC 7518 section 3.2.
+	if size := h().Size(); len(secret) < size {
+		return nil, "", fmt.Errorf("jws: HMAC secret for %s must be at least %d bytes", alg, size)
+	}
+
+	return func(data []byte) ([]byte, error) {
+		mac := hmac.New(h, secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	}, alg, nil
+}
+
+// HalfHash returns the base64url encoding of the left-most half of the hash of
+// value, using the hash function of the JWS algorithm alg. It's used for the
+// OpenID Connect 1.0 'at_hash' and 'c_hash' claims. The EdDSA algorithm uses
+// SHA-512 as it's only used with Ed25519 keys.
+func HalfHash(alg, value string) (string, error) {
+	var h crypto.Hash
+
+	switch alg {
+	case RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, ES512, HS256, HS384, HS512:
+		h = hashForAlgorithm(alg)
+	case EdDSA:
+		h = crypto.SHA512
+	default:
+		return "", fmt.Errorf("jws: unsupported algorithm %q", alg)
+	}
+
+	sum := digest(h, []byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
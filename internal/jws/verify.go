@@ -0,0 +1,103 @@
+package jws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// DecodeHeader decodes the header of a JWS in compact serialization without
+// verifying its signature.
+func DecodeHeader(token string) (*Header, error) {
+	header, _, _, ok := parseToken(token)
+	if !ok {
+		return nil, errors.New("jws: invalid token received, token must have 3 parts")
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+	h := &Header{}
+	if err = json.NewDecoder(bytes.NewBuffer(decoded)).Decode(h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// VerifyWithKey tests whether the JWS signature of token was produced with the
+// private key associated with key using the asymmetric algorithm alg, and
+// returns the decoded payload. The alg must match the token's header, which
+// prevents algorithm substitution.
+func VerifyWithKey(token, alg string, key crypto.PublicKey) ([]byte, error) {
+	header, claims, sig, ok := parseToken(token)
+	if !ok {
+		return nil, errors.New("jws: invalid token received, token must have 3 parts")
+	}
+
+	h, err := DecodeHeader(token)
+	if err != nil {
+		return nil, err
+	}
+	if h.Algorithm != alg {
+		return nil, fmt.Errorf("jws: token algorithm %q does not match expected algorithm %q", h.Algorithm, alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := []byte(header + tokenDelim + claims)
+
+	switch alg {
+	case RS256, RS384, RS512, PS256, PS384, PS512:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("jws: algorithm %s requires an RSA key but got %T", alg, key)
+		}
+		hash := hashForAlgorithm(alg)
+		if alg[0] == 'P' {
+			err = rsa.VerifyPSS(pub, hash, digest(hash, signed), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest(hash, signed), signature)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jws: invalid signature: %v", err)
+		}
+	case ES256, ES384, ES512:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("jws: algorithm %s requires an ECDSA key but got %T", alg, key)
+		}
+		if expected, _ := AlgorithmForKey(pub); expected != alg {
+			return nil, fmt.Errorf("jws: algorithm %s does not match the elliptic curve %q", alg, pub.Curve.Params().Name)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return nil, errors.New("jws: invalid signature: invalid ECDSA signature length")
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest(hashForAlgorithm(alg), signed), r, s) {
+			return nil, errors.New("jws: invalid signature")
+		}
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("jws: algorithm %s requires an Ed25519 key but got %T", alg, key)
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return nil, errors.New("jws: invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("jws: unsupported verification algorithm %q", alg)
+	}
+
+	return base64.RawURLEncoding.DecodeString(claims)
+}
@@ -33,7 +33,9 @@ type PushedAuthResponse struct {
 // and must be used within 'expires_in'
 //
 // Client authentication is handled similar to the token endpoint. See https://datatracker.ietf.org/doc/html/rfc9126#section-2.
-func RetrievePushedAuthResponse(ctx context.Context, clientID, clientSecret, parURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) (*PushedAuthResponse, error) {
+func RetrievePushedAuthResponse(ctx context.Context, auth *ClientAuth, parURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) (*PushedAuthResponse, error) {
+	ctx = auth.context(ctx)
+
 	// Client authentication for the PAR Endpoint follows the same rules as the token endpoint.
 	// A separate key (parURL) is used in the authStyle cache to account for potential variations in authorization server implementations.
 	needsAuthStyleProbe := authStyle == 0
@@ -47,16 +49,14 @@ func RetrievePushedAuthResponse(ctx context.Context, clientID, clientSecret, par
 	}
 
 	// PAR request is identical to token request except for URL.
-	req, err := newPOSTRequest(parURL, clientID, clientSecret, v, authStyle)
-	if err != nil {
-		return nil, err
+	newRequest := func() (*http.Request, error) {
+		return newPOSTRequest(parURL, auth, v, authStyle)
 	}
 
-	parResponse, err := doPARRoundTrip(ctx, req)
+	parResponse, err := roundTripWithRetry(ctx, auth, true, EndpointKindPushedAuth, "", newRequest, doPARRoundTrip)
 	if err != nil && needsAuthStyleProbe {
 		authStyle = AuthStyleInParams // the second way we'll try
-		req, _ = newPOSTRequest(parURL, clientID, clientSecret, v, authStyle)
-		parResponse, err = doPARRoundTrip(ctx, req)
+		parResponse, err = roundTripWithRetry(ctx, auth, true, EndpointKindPushedAuth, "", newRequest, doPARRoundTrip)
 	}
 
 	if needsAuthStyleProbe && err == nil {
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The retry policy defaults, see oauth2.RetryPolicy.
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryMinBackoff  = 500 * time.Millisecond
+	DefaultRetryMaxBackoff  = 10 * time.Second
+)
+
+// RetryPolicy mirrors oauth2.RetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts        int
+	MinBackoff         time.Duration
+	MaxBackoff         time.Duration
+	RetryNonIdempotent bool
+}
+
+// IsTemporary reports whether a response with the HTTP status code and RFC
+// 6749 error code indicates the server is temporarily unable to handle the
+// request, so that it may be retried.
+func IsTemporary(statusCode int, errorCode string) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return errorCode == "temporarily_unavailable"
+}
+
+// statusCode returns the status code of r, or zero if r is nil.
+func statusCode(r *http.Response) int {
+	if r == nil {
+		return 0
+	}
+
+	return r.StatusCode
+}
+
+// responseError is implemented by the errors of unsuccessful responses, both
+// those of this package and the oauth2 package.
+type responseError interface {
+	Temporary() bool
+	GetResponse() *http.Response
+}
+
+// retryable reports whether the request which failed with err may be retried,
+// and the delay requested by the server's Retry-After header, if any.
+func retryable(err error) (retry bool, after time.Duration) {
+	var rErr responseError
+	if errors.As(err, &rErr) {
+		if !rErr.Temporary() {
+			return false, 0
+		}
+
+		return true, retryAfter(rErr.GetResponse())
+	}
+
+	// Network errors, but not those of the request's context.
+	var uErr *url.Error
+
+	return errors.As(err, &uErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), 0
+}
+
+// retryAfter returns the delay of the Retry-After header of r, which is
+// either a number of seconds or an HTTP date.
+// https://datatracker.ietf.org/doc/html/rfc9110#section-10.2.3
+func retryAfter(r *http.Response) time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	value := r.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// backoff returns the jittered delay before the retry following the given
+// number of attempts.
+func (p *RetryPolicy) backoff(attempts int) time.Duration {
+	minBackoff, maxBackoff := p.MinBackoff, p.maxBackoff()
+	if minBackoff <= 0 {
+		minBackoff = DefaultRetryMinBackoff
+	}
+
+	backoff := minBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	// Equal jitter keeps the delay between half and all of the backoff.
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// maxBackoff returns the maximum delay between retries.
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return DefaultRetryMaxBackoff
+	}
+
+	return p.MaxBackoff
+}
+
+// Retry calls attempt until it succeeds or fails with an error which isn't
+// transient. Failed attempts are retried according to the policy p with
+// capped exponential backoff, or after the delay requested by the server's
+// Retry-After header unless it exceeds the maximum backoff. No retry is made
+// which would start after ctx's deadline, and requests which aren't
+// idempotent are only retried when the policy allows it. If p is nil, attempt
+// is called once.
+func Retry(ctx context.Context, p *RetryPolicy, idempotent bool, attempt func() error) error {
+	if p == nil || (!idempotent && !p.RetryNonIdempotent) {
+		return attempt()
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+
+	for attempts := 1; ; attempts++ {
+		err := attempt()
+		if err == nil || attempts >= maxAttempts {
+			return err
+		}
+
+		retry, delay := retryable(err)
+		if !retry {
+			return err
+		}
+
+		if delay <= 0 {
+			delay = p.backoff(attempts)
+		} else if delay > p.maxBackoff() {
+			// The server asks for a longer delay than the policy allows.
+			return err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// roundTripWithRetry makes the request created by newRequest with roundTrip,
+// retrying it according to the policy of auth. A new request is created for
+// each attempt, so each has a fresh client assertion. Each attempt is observed
+// by the hooks of ctx as a request to the endpoint with the grant type.
+func roundTripWithRetry[T any](ctx context.Context, auth *ClientAuth, idempotent bool, endpoint, grantType string, newRequest func() (*http.Request, error), roundTrip func(context.Context, *http.Request) (T, error)) (result T, err error) {
+	err = Retry(ctx, auth.Retry, idempotent, func() error {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+
+		result, err = Observe(ctx, endpoint, grantType, func(ctx context.Context) (T, error) {
+			return roundTrip(ctx, req)
+		})
+
+		return err
+	})
+
+	return result, err
+}
@@ -10,7 +10,9 @@ import (
 	"net/url"
 )
 
-func RevokeToken(ctx context.Context, clientID, clientSecret, revocationURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) error {
+func RevokeToken(ctx context.Context, auth *ClientAuth, revocationURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) error {
+	ctx = auth.context(ctx)
+
 	needsAuthStyleProbe := authStyle == 0
 	if needsAuthStyleProbe {
 		if style, ok := styleCache.lookupAuthStyle(revocationURL); ok {
@@ -20,15 +22,23 @@ func RevokeToken(ctx context.Context, clientID, clientSecret, revocationURL stri
 			authStyle = AuthStyleInHeader // the first way we'll try
 		}
 	}
-	req, err := newPOSTRequest(revocationURL, clientID, clientSecret, v, authStyle)
-	if err != nil {
+	revoke := func() error {
+		req, err := newPOSTRequest(revocationURL, auth, v, authStyle)
+		if err != nil {
+			return err
+		}
+
+		_, err = Observe(ctx, EndpointKindRevocation, "", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, doRevokeRoundTrip(ctx, req)
+		})
+
 		return err
 	}
 
-	if err = doRevokeRoundTrip(ctx, req); err != nil && needsAuthStyleProbe {
+	err := Retry(ctx, auth.Retry, true, revoke)
+	if err != nil && needsAuthStyleProbe {
 		authStyle = AuthStyleInParams // the second way we'll try
-		req, _ = newPOSTRequest(revocationURL, clientID, clientSecret, v, authStyle)
-		err = doRevokeRoundTrip(ctx, req)
+		err = Retry(ctx, auth.Retry, true, revoke)
 	}
 	if needsAuthStyleProbe && err == nil {
 		styleCache.setAuthStyle(revocationURL, authStyle)
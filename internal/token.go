@@ -45,6 +45,9 @@ type Token struct {
 	// IDToken is the OpenID Connect 1.0 ID Token.
 	IDToken string
 
+	// IssuedTokenType is the RFC 8693 type of the issued token.
+	IssuedTokenType string
+
 	// Expiry is the optional expiration time of the access token.
 	//
 	// If zero, TokenSource implementations will reuse the same
@@ -61,11 +64,12 @@ type Token struct {
 // providers returning a token or error in JSON form.
 // https://datatracker.ietf.org/doc/html/rfc6749#section-5.1
 type tokenJSON struct {
-	AccessToken  string         `json:"access_token"`
-	TokenType    string         `json:"token_type"`
-	RefreshToken string         `json:"refresh_token"`
-	IDToken      string         `json:"id_token"`
-	ExpiresIn    expirationTime `json:"expires_in"` // at least PayPal returns string, while most return number
+	AccessToken     string         `json:"access_token"`
+	TokenType       string         `json:"token_type"`
+	RefreshToken    string         `json:"refresh_token"`
+	IDToken         string         `json:"id_token"`
+	IssuedTokenType string         `json:"issued_token_type"`
+	ExpiresIn       expirationTime `json:"expires_in"` // at least PayPal returns string, while most return number
 	// error fields
 	// https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
 	ErrorCode        string `json:"error"`
@@ -120,9 +124,14 @@ func RegisterBrokenAuthHeaderProvider(tokenURL string) {}
 type AuthStyle int
 
 const (
-	AuthStyleUnknown  AuthStyle = 0
-	AuthStyleInParams AuthStyle = 1
-	AuthStyleInHeader AuthStyle = 2
+	AuthStyleUnknown         AuthStyle = 0
+	AuthStyleInParams        AuthStyle = 1
+	AuthStyleInHeader        AuthStyle = 2
+	AuthStylePrivateKeyJWT   AuthStyle = 3
+	AuthStyleClientSecretJWT AuthStyle = 4
+
+	AuthStyleTLSClientAuth           AuthStyle = 5
+	AuthStyleSelfSignedTLSClientAuth AuthStyle = 6
 )
 
 // LazyAuthStyleCache is a backwards compatibility compromise to let Configs
@@ -182,22 +191,39 @@ func (c *AuthStyleCache) setAuthStyle(tokenURL string, v AuthStyle) {
 }
 
 // newPOSTRequest returns a new *http.Request to retrieve a new token
-// or revoke an existing one using the uri and the provided clientID,
-// clientSecret, and POST body parameters.
+// or revoke an existing one using the uri and the provided client
+// authentication, and POST body parameters.
 //
-// inParams is whether the clientID & clientSecret should be encoded
-// as the POST body. An 'inParams' value of true means to send it in
-// the POST body (along with any values in v); false means to send it
-// in the Authorization header.
-func newPOSTRequest(uri, clientID, clientSecret string, v url.Values, authStyle AuthStyle) (*http.Request, error) {
-	if authStyle == AuthStyleInParams {
+// authStyle determines how the client authenticates. AuthStyleInParams
+// sends the client_id & client_secret in the POST body (along with any
+// values in v), AuthStyleInHeader sends them in the Authorization header,
+// and AuthStylePrivateKeyJWT sends the client_id and a signed client
+// assertion in the POST body.
+func newPOSTRequest(uri string, auth *ClientAuth, v url.Values, authStyle AuthStyle) (*http.Request, error) {
+	switch authStyle {
+	case AuthStyleInParams:
 		v = cloneURLValues(v)
-		if clientID != "" {
-			v.Set("client_id", clientID)
+		if auth.ClientID != "" {
+			v.Set("client_id", auth.ClientID)
+		}
+		if auth.ClientSecret != "" {
+			v.Set("client_secret", auth.ClientSecret)
+		}
+	case AuthStylePrivateKeyJWT, AuthStyleClientSecretJWT:
+		assertion, err := auth.assertion(uri, authStyle)
+		if err != nil {
+			return nil, err
 		}
-		if clientSecret != "" {
-			v.Set("client_secret", clientSecret)
+		v = cloneURLValues(v)
+		v.Set("client_id", auth.ClientID)
+		v.Set("client_assertion_type", ClientAssertionType)
+		v.Set("client_assertion", assertion)
+	case AuthStyleTLSClientAuth, AuthStyleSelfSignedTLSClientAuth:
+		if auth.Certificate == nil {
+			return nil, errors.New("oauth2: auth style requires a client certificate")
 		}
+		v = cloneURLValues(v)
+		v.Set("client_id", auth.ClientID)
 	}
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(v.Encode()))
 	if err != nil {
@@ -205,7 +231,7 @@ func newPOSTRequest(uri, clientID, clientSecret string, v url.Values, authStyle
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	if authStyle == AuthStyleInHeader {
-		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+		req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
 	}
 	return req, nil
 }
@@ -218,7 +244,9 @@ func cloneURLValues(v url.Values) url.Values {
 	return v2
 }
 
-func RetrieveToken(ctx context.Context, clientID, clientSecret, tokenURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) (*Token, error) {
+func RetrieveToken(ctx context.Context, auth *ClientAuth, tokenURL string, v url.Values, authStyle AuthStyle, styleCache *AuthStyleCache) (*Token, error) {
+	ctx = auth.context(ctx)
+
 	needsAuthStyleProbe := authStyle == 0
 	if needsAuthStyleProbe {
 		if style, ok := styleCache.lookupAuthStyle(tokenURL); ok {
@@ -229,12 +257,17 @@ func RetrieveToken(ctx context.Context, clientID, clientSecret, tokenURL string,
 		}
 	}
 
-	req, err := newPOSTRequest(tokenURL, clientID, clientSecret, v, authStyle)
-	if err != nil {
-		return nil, err
+	// An authorization code may only be used once, and a rotated refresh
+	// token is revoked once used, so a request which reached the server
+	// before failing can't be retried.
+	grantType := v.Get("grant_type")
+	idempotent := grantType != "authorization_code" && grantType != "refresh_token"
+
+	newRequest := func() (*http.Request, error) {
+		return newPOSTRequest(tokenURL, auth, v, authStyle)
 	}
 
-	token, err := doTokenRoundTrip(ctx, req)
+	token, err := roundTripWithRetry(ctx, auth, idempotent, EndpointKindToken, v.Get("grant_type"), newRequest, doTokenRoundTrip)
 	if err != nil && needsAuthStyleProbe {
 		// If we get an error, assume the server wants the
 		// clientID & clientSecret in a different form.
@@ -249,8 +282,7 @@ func RetrieveToken(ctx context.Context, clientID, clientSecret, tokenURL string,
 		// they went, but maintaining it didn't scale & got annoying.
 		// So just try both ways.
 		authStyle = AuthStyleInParams // the second way we'll try
-		req, _ = newPOSTRequest(tokenURL, clientID, clientSecret, v, authStyle)
-		token, err = doTokenRoundTrip(ctx, req)
+		token, err = roundTripWithRetry(ctx, auth, idempotent, EndpointKindToken, v.Get("grant_type"), newRequest, doTokenRoundTrip)
 	}
 
 	if needsAuthStyleProbe && err == nil {
@@ -300,11 +332,12 @@ func doTokenRoundTrip(ctx context.Context, req *http.Request) (*Token, error) {
 		retrieveError.ErrorDescription = vals.Get("error_description")
 		retrieveError.ErrorURI = vals.Get("error_uri")
 		token = &Token{
-			AccessToken:  vals.Get("access_token"),
-			TokenType:    vals.Get("token_type"),
-			RefreshToken: vals.Get("refresh_token"),
-			IDToken:      vals.Get("id_token"),
-			Raw:          vals,
+			AccessToken:     vals.Get("access_token"),
+			TokenType:       vals.Get("token_type"),
+			RefreshToken:    vals.Get("refresh_token"),
+			IDToken:         vals.Get("id_token"),
+			IssuedTokenType: vals.Get("issued_token_type"),
+			Raw:             vals,
 		}
 		e := vals.Get("expires_in")
 		expires, _ := strconv.Atoi(e)
@@ -323,12 +356,13 @@ func doTokenRoundTrip(ctx context.Context, req *http.Request) (*Token, error) {
 		retrieveError.ErrorDescription = tj.ErrorDescription
 		retrieveError.ErrorURI = tj.ErrorURI
 		token = &Token{
-			AccessToken:  tj.AccessToken,
-			TokenType:    tj.TokenType,
-			RefreshToken: tj.RefreshToken,
-			IDToken:      tj.IDToken,
-			Expiry:       tj.expiry(),
-			Raw:          make(map[string]any),
+			AccessToken:     tj.AccessToken,
+			TokenType:       tj.TokenType,
+			RefreshToken:    tj.RefreshToken,
+			IDToken:         tj.IDToken,
+			IssuedTokenType: tj.IssuedTokenType,
+			Expiry:          tj.expiry(),
+			Raw:             make(map[string]any),
 		}
 		_ = json.Unmarshal(body, &token.Raw) // no error checks for optional fields
 	}
@@ -355,6 +389,19 @@ type RetrieveError struct {
 	ErrorURI         string
 }
 
+// Temporary reports whether the request may be retried.
+func (r *RetrieveError) Temporary() bool {
+	return IsTemporary(statusCode(r.Response), r.ErrorCode)
+}
+
+func (r *RetrieveError) GetResponse() *http.Response {
+	return r.Response
+}
+
+func (r *RetrieveError) GetErrorCode() string {
+	return r.ErrorCode
+}
+
 func (r *RetrieveError) Error() string {
 	if r.ErrorCode != "" {
 		s := fmt.Sprintf("oauth2: %q", r.ErrorCode)
@@ -366,7 +413,7 @@ func (r *RetrieveError) Error() string {
 		}
 		return s
 	}
-	return fmt.Sprintf("oauth2: cannot fetch token: %v\nResponse: %s", r.Response.Status, r.Body)
+	return fmt.Sprintf("oauth2: cannot fetch token: %v", r.Response.Status)
 }
 
 type RevokeError struct {
@@ -377,6 +424,19 @@ type RevokeError struct {
 	ErrorURI         string
 }
 
+// Temporary reports whether the request may be retried.
+func (r *RevokeError) Temporary() bool {
+	return IsTemporary(statusCode(r.Response), r.ErrorCode)
+}
+
+func (r *RevokeError) GetResponse() *http.Response {
+	return r.Response
+}
+
+func (r *RevokeError) GetErrorCode() string {
+	return r.ErrorCode
+}
+
 func (r *RevokeError) Error() string {
 	if r.ErrorCode != "" {
 		s := fmt.Sprintf("oauth2: %q", r.ErrorCode)
@@ -388,5 +448,5 @@ func (r *RevokeError) Error() string {
 		}
 		return s
 	}
-	return fmt.Sprintf("oauth2: cannot revoke token: %v\nResponse: %s", r.Response.Status, r.Body)
+	return fmt.Sprintf("oauth2: cannot revoke token: %v", r.Response.Status)
 }
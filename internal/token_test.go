@@ -6,6 +6,9 @@ package internal
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"math"
@@ -13,6 +16,8 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+
+	"authelia.com/client/oauth2/internal/jws"
 )
 
 func TestRetrieveToken_InParams(t *testing.T) {
@@ -29,7 +34,53 @@ func TestRetrieveToken_InParams(t *testing.T) {
 		io.WriteString(w, `{"access_token": "ACCESS_TOKEN", "token_type": "bearer"}`)
 	}))
 	defer ts.Close()
-	_, err := RetrieveToken(context.Background(), clientID, "", ts.URL, url.Values{}, AuthStyleInParams, styleCache)
+	_, err := RetrieveToken(context.Background(), &ClientAuth{ClientID: clientID}, ts.URL, url.Values{}, AuthStyleInParams, styleCache)
+	if err != nil {
+		t.Errorf("RetrieveToken = %v; want no error", err)
+	}
+}
+
+func TestRetrieveToken_PrivateKeyJWT(t *testing.T) {
+	styleCache := new(AuthStyleCache)
+	const clientID = "client-id"
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokenURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Error("unexpected basic auth header")
+		}
+		if got, want := r.FormValue("client_id"), clientID; got != want {
+			t.Errorf("client_id = %q; want %q", got, want)
+		}
+		if got := r.FormValue("client_secret"); got != "" {
+			t.Errorf("client_secret = %q; want empty", got)
+		}
+		if got, want := r.FormValue("client_assertion_type"), ClientAssertionType; got != want {
+			t.Errorf("client_assertion_type = %q; want %q", got, want)
+		}
+		claims, err := jws.Decode(r.FormValue("client_assertion"))
+		if err != nil {
+			t.Fatalf("jws.Decode = %v", err)
+		}
+		if claims.Iss != clientID || claims.Sub != clientID {
+			t.Errorf("iss, sub = %q, %q; want %q", claims.Iss, claims.Sub, clientID)
+		}
+		if claims.Aud != tokenURL {
+			t.Errorf("aud = %q; want %q", claims.Aud, tokenURL)
+		}
+		if claims.Jti == "" {
+			t.Error("jti is empty")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token": "ACCESS_TOKEN", "token_type": "bearer"}`)
+	}))
+	defer ts.Close()
+	tokenURL = ts.URL + "/token"
+	auth := &ClientAuth{ClientID: clientID, PrivateKey: key, PrivateKeyID: "kid"}
+	_, err = RetrieveToken(context.Background(), auth, tokenURL, url.Values{}, AuthStylePrivateKeyJWT, styleCache)
 	if err != nil {
 		t.Errorf("RetrieveToken = %v; want no error", err)
 	}
@@ -45,7 +96,7 @@ func TestRetrieveTokenWithContexts(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	_, err := RetrieveToken(context.Background(), clientID, "", ts.URL, url.Values{}, AuthStyleUnknown, styleCache)
+	_, err := RetrieveToken(context.Background(), &ClientAuth{ClientID: clientID}, ts.URL, url.Values{}, AuthStyleUnknown, styleCache)
 	if err != nil {
 		t.Errorf("RetrieveToken (with background context) = %v; want no error", err)
 	}
@@ -58,7 +109,7 @@ func TestRetrieveTokenWithContexts(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	_, err = RetrieveToken(ctx, clientID, "", cancellingts.URL, url.Values{}, AuthStyleUnknown, styleCache)
+	_, err = RetrieveToken(ctx, &ClientAuth{ClientID: clientID}, cancellingts.URL, url.Values{}, AuthStyleUnknown, styleCache)
 	close(retrieved)
 	if err == nil {
 		t.Errorf("RetrieveToken (with cancelled context) = nil; want error")
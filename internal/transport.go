@@ -5,8 +5,12 @@
 package internal
 
 import (
+	"container/list"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"net/http"
+	"sync"
 )
 
 // HTTPClient is the context key to use with golang.org/x/net/context's
@@ -27,3 +31,122 @@ func ContextClient(ctx context.Context) *http.Client {
 
 	return http.DefaultClient
 }
+
+// ContextWithCertificate returns a copy of ctx whose HTTP client presents cert
+// as the TLS client certificate, as done by CertificateClient. If cert is nil,
+// ctx is returned unchanged.
+func ContextWithCertificate(ctx context.Context, cert *tls.Certificate) context.Context {
+	if cert == nil {
+		return ctx
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, HTTPClient, CertificateClient(ContextClient(ctx), cert))
+}
+
+// maxCertTransports is the number of transports kept by certTransports.
+const maxCertTransports = 16
+
+// certTransports caches the transports built by CertificateClient so that
+// connections are reused between requests made with the same certificate.
+var certTransports = &transportCache{max: maxCertTransports}
+
+// certTransportKey identifies a certificate by the hash of its chain rather
+// than its address, as the callers may build a new tls.Certificate for each
+// request.
+type certTransportKey struct {
+	base  *http.Transport
+	chain [sha256.Size]byte
+}
+
+// transportCache is a cache of the max most recently used transports. The
+// idle connections of the transports evicted from it are closed.
+type transportCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[certTransportKey]*list.Element // of *transportCacheEntry
+	lru     *list.List                         // most recently used first
+}
+
+type transportCacheEntry struct {
+	key certTransportKey
+	tr  *http.Transport
+}
+
+// get returns the cached transport of key, or caches the transport returned
+// by build.
+func (c *transportCache) get(key certTransportKey, build func() *http.Transport) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*transportCacheEntry).tr
+	}
+
+	tr := build()
+
+	if c.entries == nil {
+		c.entries = make(map[certTransportKey]*list.Element)
+		c.lru = list.New()
+	}
+
+	c.entries[key] = c.lru.PushFront(&transportCacheEntry{key: key, tr: tr})
+
+	if c.lru.Len() > c.max {
+		oldest := c.lru.Remove(c.lru.Back()).(*transportCacheEntry)
+		delete(c.entries, oldest.key)
+		oldest.tr.CloseIdleConnections()
+	}
+
+	return tr
+}
+
+// CertificateClient returns a shallow copy of hc whose transport presents
+// cert as the TLS client certificate. If the transport of hc isn't an
+// *http.Transport, hc is returned unchanged and its transport is responsible
+// for presenting the certificate.
+func CertificateClient(hc *http.Client, cert *tls.Certificate) *http.Client {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ht, ok := base.(*http.Transport)
+	if !ok {
+		return hc
+	}
+
+	tr := certTransports.get(certTransportKey{base: ht, chain: chainHash(cert)}, func() *http.Transport {
+		tr := ht.Clone()
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+
+		return tr
+	})
+
+	hc2 := *hc
+	hc2.Transport = tr
+	return &hc2
+}
+
+// chainHash returns the hash of the DER encoded certificate chain of cert.
+func chainHash(cert *tls.Certificate) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, der := range cert.Certificate {
+		// The length prefix keeps the boundaries of the certificates unambiguous.
+		h.Write([]byte{byte(len(der) >> 24), byte(len(der) >> 16), byte(len(der) >> 8), byte(len(der))})
+		h.Write(der)
+	}
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+
+	return sum
+}
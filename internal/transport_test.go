@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+)
+
+func TestCertificateClientCache(t *testing.T) {
+	hc := &http.Client{Transport: &http.Transport{}}
+	first := &tls.Certificate{Certificate: [][]byte{[]byte("first")}}
+
+	c1 := CertificateClient(hc, first)
+	if c2 := CertificateClient(hc, first); c1.Transport != c2.Transport {
+		t.Error("CertificateClient with the same certificate returned a different transport")
+	}
+
+	// Certificates are identified by their chain rather than their address.
+	if c2 := CertificateClient(hc, &tls.Certificate{Certificate: [][]byte{[]byte("first")}}); c1.Transport != c2.Transport {
+		t.Error("CertificateClient with a copy of the certificate returned a different transport")
+	}
+	if c2 := CertificateClient(hc, &tls.Certificate{Certificate: [][]byte{[]byte("fir"), []byte("st")}}); c1.Transport == c2.Transport {
+		t.Error("CertificateClient with a different chain returned the same transport")
+	}
+
+	// The least recently used transports are evicted.
+	for i := 0; i < maxCertTransports; i++ {
+		CertificateClient(hc, &tls.Certificate{Certificate: [][]byte{{byte(i)}}})
+	}
+	if c3 := CertificateClient(hc, first); c3.Transport == c1.Transport {
+		t.Error("CertificateClient returned an evicted transport")
+	}
+	if n := certTransports.lru.Len(); n != maxCertTransports {
+		t.Errorf("Cached transports = %d; want %d", n, maxCertTransports)
+	}
+
+	// Other transports are responsible for presenting the certificate.
+	custom := &http.Client{Transport: roundTripFunc(nil)}
+	if c := CertificateClient(custom, first); c != custom {
+		t.Error("CertificateClient with a custom transport didn't return the client unchanged")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,158 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// IntrospectionResponse describes an RFC 7662 Token Introspection Response.
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.2
+type IntrospectionResponse struct {
+	// Active indicates whether the token is currently active. When false the
+	// other fields are typically empty.
+	Active bool `json:"active"`
+	// Scope is the space-separated list of scopes associated with the token.
+	Scope string `json:"scope,omitempty"`
+	// ClientID is the identifier of the client the token was issued to.
+	ClientID string `json:"client_id,omitempty"`
+	// Username is the human-readable identifier of the resource owner.
+	Username string `json:"username,omitempty"`
+	// TokenType is the type of the token, such as Bearer or DPoP.
+	TokenType string `json:"token_type,omitempty"`
+	// Expiry is when the token expires.
+	Expiry time.Time `json:"-"`
+	// IssuedAt is when the token was issued.
+	IssuedAt time.Time `json:"-"`
+	// NotBefore is when the token becomes valid.
+	NotBefore time.Time `json:"-"`
+	// Subject is the subject of the token, usually the resource owner.
+	Subject string `json:"sub,omitempty"`
+	// Audience is the intended audience of the token.
+	Audience []string `json:"-"`
+	// Issuer is the issuer of the token.
+	Issuer string `json:"iss,omitempty"`
+	// JWTID is the unique identifier of the token.
+	JWTID string `json:"jti,omitempty"`
+	// Confirmation is the confirmation method binding the token to a key or
+	// certificate of the client, as described in RFC 8705 and RFC 9449.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	// raw contains every member of the response.
+	raw map[string]any
+}
+
+// Confirmation describes the 'cnf' claim binding a token to the client.
+type Confirmation struct {
+	// X509CertificateSHA256Thumbprint is the base64url encoded SHA-256
+	// thumbprint of the client certificate the token is bound to.
+	X509CertificateSHA256Thumbprint string `json:"x5t#S256,omitempty"`
+	// JWKThumbprint is the base64url encoded JWK SHA-256 thumbprint of the
+	// DPoP key the token is bound to.
+	JWKThumbprint string `json:"jkt,omitempty"`
+}
+
+func (r *IntrospectionResponse) UnmarshalJSON(data []byte) (err error) {
+	type Alias IntrospectionResponse
+
+	aux := &struct {
+		Expiry    jws.NumericDate `json:"exp"`
+		IssuedAt  jws.NumericDate `json:"iat"`
+		NotBefore jws.NumericDate `json:"nbf"`
+		Audience  json.RawMessage `json:"aud"`
+		*Alias
+	}{
+		Alias: (*Alias)(r),
+	}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.Expiry = aux.Expiry.Time()
+	r.IssuedAt = aux.IssuedAt.Time()
+	r.NotBefore = aux.NotBefore.Time()
+
+	if r.Audience, err = jws.ParseAudience(aux.Audience); err != nil {
+		return fmt.Errorf("oauth2: cannot parse introspection aud: %w", err)
+	}
+
+	return json.Unmarshal(data, &r.raw)
+}
+
+// Extra returns a member of the introspection response, including any which
+// are not represented by the IntrospectionResponse fields.
+func (r *IntrospectionResponse) Extra(key string) any {
+	return r.raw[key]
+}
+
+// IntrospectToken performs RFC 7662 token introspection of token at the
+// Endpoint.IntrospectionURL. The client authenticates in the same manner as
+// it does to the token endpoint.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func (c *Config) IntrospectToken(ctx context.Context, token string, opts ...IntrospectionOption) (*IntrospectionResponse, error) {
+	if token == "" {
+		return nil, errors.New("error introspecting token: no token was provided")
+	}
+
+	if c.introspectionURL() == "" {
+		return nil, errors.New("error introspecting token: no introspection endpoint URL was provided")
+	}
+
+	v := url.Values{
+		"token": {token},
+	}
+
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+
+	body, err := internal.IntrospectToken(ctx, c.clientAuth(), c.introspectionURL(), v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get())
+	if err != nil {
+		var iErr *internal.IntrospectionError
+
+		if errors.As(err, &iErr) {
+			return nil, &IntrospectionError{BaseError: (*BaseError)(iErr)}
+		}
+
+		return nil, err
+	}
+
+	ir := &IntrospectionResponse{}
+
+	if err = json.Unmarshal(body, ir); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse introspection response: %w", err)
+	}
+
+	return ir, nil
+}
+
+// IntrospectionError is the error returned when the introspection endpoint
+// returns a non-2XX HTTP status code or populates RFC 6749's 'error' parameter.
+type IntrospectionError struct {
+	*BaseError
+}
+
+// An IntrospectionOption is passed to Config.IntrospectToken.
+type IntrospectionOption interface {
+	setValue(vals url.Values)
+}
+
+// SetIntrospectionURLParam builds an IntrospectionOption which passes key/value
+// parameters to a provider's introspection endpoint.
+func SetIntrospectionURLParam(key, value string) IntrospectionOption {
+	return setParam{key, value}
+}
+
+// IntrospectionTokenTypeHint builds an IntrospectionOption which sets the
+// 'token_type_hint' parameter, such as "access_token" or "refresh_token".
+func IntrospectionTokenTypeHint(hint string) IntrospectionOption {
+	return setParam{"token_type_hint", hint}
+}
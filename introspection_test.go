@@ -0,0 +1,70 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIntrospectToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "/introspect" {
+			t.Errorf("Unexpected introspection URL %q", r.URL)
+		}
+		if want := "Basic Q0xJRU5UX0lEOkNMSUVOVF9TRUNSRVQ="; r.Header.Get("Authorization") != want {
+			t.Errorf("Unexpected authorization header %q, want %q", r.Header.Get("Authorization"), want)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "token=ACCESS_TOKEN&token_type_hint=access_token" {
+			t.Errorf("Unexpected introspection payload; got %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"active":true,"scope":"openid profile","client_id":"CLIENT_ID","username":"john","token_type":"Bearer","exp":1700000000,"iat":1600000000.5,"sub":"abc","aud":"api","iss":"https://issuer","jti":"123","cnf":{"x5t#S256":"thumb"},"acr":"mfa"}`)
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.Endpoint.IntrospectionURL = ts.URL + "/introspect"
+	ir, err := conf.IntrospectToken(context.Background(), "ACCESS_TOKEN", IntrospectionTokenTypeHint("access_token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Active || ir.Scope != "openid profile" || ir.ClientID != "CLIENT_ID" || ir.Username != "john" || ir.Subject != "abc" || ir.Issuer != "https://issuer" || ir.JWTID != "123" {
+		t.Errorf("Unexpected introspection response %+v", ir)
+	}
+	if !ir.Expiry.Equal(time.Unix(1700000000, 0)) || !ir.IssuedAt.Equal(time.Unix(1600000000, 5e8)) || !ir.NotBefore.IsZero() {
+		t.Errorf("Unexpected introspection times %v, %v, %v", ir.Expiry, ir.IssuedAt, ir.NotBefore)
+	}
+	if len(ir.Audience) != 1 || ir.Audience[0] != "api" {
+		t.Errorf("Unexpected audience %v", ir.Audience)
+	}
+	if ir.Confirmation == nil || ir.Confirmation.X509CertificateSHA256Thumbprint != "thumb" {
+		t.Errorf("Unexpected confirmation %+v", ir.Confirmation)
+	}
+	if got := ir.Extra("acr"); got != "mfa" {
+		t.Errorf("Extra(acr) = %v; want mfa", got)
+	}
+}
+
+func TestIntrospectTokenError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error": "invalid_client", "error_description": "bad client"}`)
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.Endpoint.IntrospectionURL = ts.URL + "/introspect"
+	conf.Endpoint.AuthStyle = AuthStyleInHeader
+	_, err := conf.IntrospectToken(context.Background(), "ACCESS_TOKEN")
+	var iErr *IntrospectionError
+	if !errors.As(err, &iErr) {
+		t.Fatalf("got %T error, expected *IntrospectionError; error was: %v", err, err)
+	}
+	if iErr.ErrorCode != "invalid_client" || iErr.ErrorDescription != "bad client" {
+		t.Errorf("Unexpected error %+v", iErr.BaseError)
+	}
+}
@@ -0,0 +1,128 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// The JWT Secured Authorization Response Mode (JARM) response modes. When one
+// of them is passed to AuthCodeURL or PushedAuth, the authorization response
+// is delivered as a signed, and optionally encrypted, JWT in the 'response'
+// parameter which is parsed with ParseJARMRequest or ParseJARM.
+//
+// See https://openid.net/specs/oauth-v2-jarm.html.
+var (
+	ResponseModeJWT         AuthCodeOption = SetAuthURLParam("response_mode", "jwt")
+	ResponseModeQueryJWT    AuthCodeOption = SetAuthURLParam("response_mode", "query.jwt")
+	ResponseModeFragmentJWT AuthCodeOption = SetAuthURLParam("response_mode", "fragment.jwt")
+	ResponseModeFormPostJWT AuthCodeOption = SetAuthURLParam("response_mode", "form_post.jwt")
+)
+
+// ParseJARMRequest parses the JARM 'response' parameter of the authorization
+// response r received at the RedirectURL, delivered in the query or in a POST
+// form body. The fragment.jwt response mode requires the fragment to be
+// forwarded to the server in one of these ways. See ParseJARM.
+func (c *Config) ParseJARMRequest(r *http.Request) (*AuthorizationResponse, error) {
+	response := r.FormValue("response")
+	if response == "" {
+		return nil, errors.New("oauth2: authorization response has no 'response' parameter")
+	}
+
+	return c.ParseJARM(r.Context(), response)
+}
+
+// ParseJARM parses the JARM response JWT. An encrypted response is decrypted
+// with the DecryptionKey, and the signature is verified with the keys of the
+// Endpoint.JWKSURL. The 'iss' claim must match the Endpoint.Issuer, the 'aud'
+// claim must contain the ClientID, and the response must not be expired.
+//
+// If the response contains RFC 6749's 'error' parameter, an
+// *AuthorizationError is returned.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func (c *Config) ParseJARM(ctx context.Context, response string) (*AuthorizationResponse, error) {
+	if c.Endpoint.Issuer == "" {
+		return nil, errors.New("oauth2: cannot validate authorization response: no issuer was provided")
+	}
+
+	if jwe.IsEncrypted(response) {
+		plaintext, err := c.decrypt(response)
+		if err != nil {
+			return nil, fmt.Errorf("oauth2: cannot decrypt authorization response: %w", err)
+		}
+
+		response = string(plaintext)
+	}
+
+	ks, err := c.KeySet()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot verify authorization response: %w", err)
+	}
+
+	payload, err := ks.Verify(ctx, response)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot verify authorization response: %w", err)
+	}
+
+	var claims struct {
+		Issuer           string          `json:"iss"`
+		Audience         json.RawMessage `json:"aud"`
+		Expiry           jws.NumericDate `json:"exp"`
+		Code             string          `json:"code"`
+		State            string          `json:"state"`
+		ErrorCode        string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+		ErrorURI         string          `json:"error_uri"`
+	}
+
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse authorization response: %w", err)
+	}
+
+	if claims.Issuer != c.Endpoint.Issuer {
+		return nil, fmt.Errorf("oauth2: authorization response 'iss' claim %q does not match the issuer %q", claims.Issuer, c.Endpoint.Issuer)
+	}
+
+	if len(claims.Audience) == 0 || !audienceContains(claims.Audience, c.ClientID) {
+		return nil, fmt.Errorf("oauth2: authorization response 'aud' claim does not contain the client id %q", c.ClientID)
+	}
+
+	if claims.Expiry == 0 {
+		return nil, errors.New("oauth2: authorization response has no 'exp' claim")
+	}
+
+	// The same clock skew is allowed as when verifying ID Tokens.
+	if expiry := claims.Expiry.Time(); !timeNow().Before(expiry.Add(jws.DefaultClockSkew)) {
+		return nil, fmt.Errorf("oauth2: authorization response expired at %v", expiry)
+	}
+
+	if claims.ErrorCode != "" {
+		return nil, &AuthorizationError{
+			BaseError: &BaseError{
+				Body:             payload,
+				ErrorCode:        claims.ErrorCode,
+				ErrorDescription: claims.ErrorDescription,
+				ErrorURI:         claims.ErrorURI,
+			},
+			State: claims.State,
+		}
+	}
+
+	ar := &AuthorizationResponse{
+		Code:   claims.Code,
+		State:  claims.State,
+		Issuer: claims.Issuer,
+	}
+
+	if err = json.Unmarshal(payload, &ar.raw); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse authorization response: %w", err)
+	}
+
+	return ar, nil
+}
@@ -0,0 +1,110 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestParseJARM(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := jws.PublicJWK(signingKey.Public())
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.DecryptionKey = encryptionKey
+	conf.Endpoint.Issuer = "https://as.example.com"
+	conf.Endpoint.JWKSURL = ts.URL
+
+	sign := func(modify func(claims map[string]any)) string {
+		claims := map[string]any{
+			"iss":   "https://as.example.com",
+			"aud":   "CLIENT_ID",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"code":  "CODE",
+			"state": "STATE",
+		}
+		if modify != nil {
+			modify(claims)
+		}
+		signer, alg, _ := jws.NewSigner("", signingKey)
+		token, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg}, claims, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	r := httptest.NewRequest("POST", "/callback", strings.NewReader(url.Values{"response": {sign(nil)}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ar, err := conf.ParseJARMRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.Code != "CODE" || ar.State != "STATE" || ar.Issuer != "https://as.example.com" {
+		t.Errorf("Unexpected authorization response %+v", ar)
+	}
+
+	encrypted, err := jwe.Encrypt([]byte(sign(nil)), &jwe.Header{Algorithm: "ECDH-ES", Encryption: "A256GCM", ContentType: "JWT"}, encryptionKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar, err = conf.ParseJARMRequest(httptest.NewRequest("GET", "/callback?response="+encrypted, nil)); err != nil || ar.Code != "CODE" {
+		t.Errorf("ParseJARMRequest(encrypted) = %+v, %v; want code CODE", ar, err)
+	}
+
+	// A fractional exp within the allowed clock skew is accepted.
+	if _, err = conf.ParseJARM(context.Background(), sign(func(c map[string]any) {
+		c["exp"] = float64(time.Now().Add(-30*time.Second).Unix()) + 0.5
+	})); err != nil {
+		t.Errorf("ParseJARM(fractional exp) = %v; want nil", err)
+	}
+
+	_, err = conf.ParseJARM(context.Background(), sign(func(c map[string]any) {
+		delete(c, "code")
+		c["error"] = "access_denied"
+		c["error_description"] = "denied"
+	}))
+	var aErr *AuthorizationError
+	if !errors.As(err, &aErr) || aErr.ErrorCode != "access_denied" || aErr.ErrorDescription != "denied" || aErr.State != "STATE" {
+		t.Errorf("ParseJARM error = %v; want AuthorizationError access_denied", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]any)
+	}{
+		{"issuer", func(c map[string]any) { c["iss"] = "https://other.example.com" }},
+		{"audience", func(c map[string]any) { c["aud"] = "OTHER" }},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }},
+		{"no expiry", func(c map[string]any) { delete(c, "exp") }},
+	}
+	for _, tt := range tests {
+		if _, err = conf.ParseJARM(context.Background(), sign(tt.modify)); err == nil {
+			t.Errorf("ParseJARM with invalid %s = nil error; want error", tt.name)
+		}
+	}
+}
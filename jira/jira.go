@@ -118,7 +118,7 @@ func (js jwtSource) Token() (*oauth2.Token, error) {
 		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
 	}
 	if c := resp.StatusCode; c < 200 || c > 299 {
-		return nil, fmt.Errorf("oauth2: cannot fetch token: %v\nResponse: %s", resp.Status, body)
+		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", resp.Status)
 	}
 
 	// tokenRes is the JSON response body.
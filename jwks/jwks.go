@@ -0,0 +1,461 @@
+// Package jwks implements retrieval of JSON Web Key Sets as described in
+// RFC 7517 and the verification of JWTs signed by the keys they contain.
+package jwks // import "authelia.com/client/oauth2/jwks"
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// ErrNoKeySet is returned when a token must be verified but no key set is
+// available.
+var ErrNoKeySet = errors.New("jwks: no key set is configured")
+
+// Key is a public JSON Web Key.
+type Key struct {
+	// KeyID is the 'kid' member.
+	KeyID string
+	// Algorithm is the optional 'alg' member.
+	Algorithm string
+	// Use is the optional 'use' member, either "sig" or "enc".
+	Use string
+	// Key is the *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or
+	// *ecdh.PublicKey.
+	Key crypto.PublicKey
+	// Certificates is the optional 'x5c' certificate chain, starting with the
+	// certificate containing Key.
+	Certificates []*x509.Certificate
+}
+
+// Parse parses a JSON Web Key Set. Keys with an unsupported key type are
+// skipped as required by RFC 7517 section 5.
+func Parse(data []byte) ([]*Key, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: cannot parse key set: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(set.Keys))
+
+	for _, raw := range set.Keys {
+		jwk, err := jws.ParseJWK(raw)
+		if err != nil {
+			continue
+		}
+
+		keys = append(keys, &Key{
+			KeyID:        jwk.KeyID,
+			Algorithm:    jwk.Algorithm,
+			Use:          jwk.Use,
+			Key:          jwk.Key,
+			Certificates: jwk.Certificates,
+		})
+	}
+
+	return keys, nil
+}
+
+// DefaultRefreshInterval is the minimum interval between fetches of a KeySet
+// when its RefreshInterval is zero.
+const DefaultRefreshInterval = time.Minute
+
+// DefaultTTL is how long the keys of a KeySet are cached when its TTL is zero
+// and the response has neither a Cache-Control max-age nor an Expires header.
+const DefaultTTL = time.Hour
+
+// KeySet is a JSON Web Key Set retrieved from a URL.
+//
+// The keys are cached for as long as allowed by the Cache-Control or Expires
+// headers of the response. They're fetched again once the cache expires, or
+// when a token references a key which isn't in the set, which allows for key
+// rotation. Fetches are rate limited by the RefreshInterval, and concurrent
+// lookups share a single fetch. If a fetch fails, the expired keys continue to
+// be used.
+//
+// A KeySet is safe for concurrent use.
+type KeySet struct {
+	// RefreshInterval is the minimum interval between fetches. If zero,
+	// DefaultRefreshInterval is used.
+	RefreshInterval time.Duration
+
+	// TTL is how long the keys are cached when the response doesn't specify
+	// it. If zero, DefaultTTL is used.
+	TTL time.Duration
+
+	uri string
+
+	mu        sync.Mutex // guards the fields below
+	keys      []*Key
+	expiry    time.Time
+	lastFetch time.Time
+	inflight  *fetchCall
+}
+
+// fetchCall is an in-flight or completed fetch shared by concurrent lookups.
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// timeNow is time.Now but pulled out as a variable for tests.
+var timeNow = time.Now
+
+// NewKeySet returns a KeySet which retrieves the keys from uri.
+func NewKeySet(uri string) *KeySet {
+	return &KeySet{uri: uri}
+}
+
+// NewStaticKeySet returns a KeySet which only contains keys, and never
+// retrieves keys from a URL.
+func NewStaticKeySet(keys ...*Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// URL returns the URL the keys are retrieved from.
+func (s *KeySet) URL() string {
+	return s.uri
+}
+
+// Key returns the signing key with the key ID kid which is suitable for the
+// JWS algorithm alg. If kid is empty, the first suitable key is returned.
+//
+// The provided context optionally controls which HTTP client is used. See the
+// oauth2.HTTPClient variable.
+func (s *KeySet) Key(ctx context.Context, kid, alg string) (*Key, error) {
+	key, err := s.lookup(ctx, func(keys []*Key) *Key { return findKey(keys, kid, alg) })
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("jwks: no key found with kid %q and alg %q", kid, alg)
+	}
+
+	return key, nil
+}
+
+// EncryptionKey returns the encryption key with the key ID kid, such as the
+// key used to encrypt request objects to the authorization server. If kid is
+// empty, the first encryption key is returned.
+//
+// The provided context optionally controls which HTTP client is used. See the
+// oauth2.HTTPClient variable.
+func (s *KeySet) EncryptionKey(ctx context.Context, kid string) (*Key, error) {
+	key, err := s.lookup(ctx, func(keys []*Key) *Key { return findEncryptionKey(keys, kid) })
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("jwks: no encryption key found with kid %q", kid)
+	}
+
+	return key, nil
+}
+
+// lookup returns the key found by find, fetching the keys when they have
+// expired or when find returns nil, as long as the rate limit allows it. The
+// key is nil when it can't be found.
+func (s *KeySet) lookup(ctx context.Context, find func(keys []*Key) *Key) (*Key, error) {
+	s.mu.Lock()
+	keys, expired, canFetch := s.keys, !timeNow().Before(s.expiry), s.canFetch()
+	s.mu.Unlock()
+
+	key := find(keys)
+
+	if key != nil && !expired {
+		return key, nil
+	}
+
+	if canFetch {
+		if err := s.refresh(ctx); err != nil {
+			// If the fetch fails, the expired key is used.
+			if key != nil {
+				return key, nil
+			}
+
+			return nil, err
+		}
+
+		s.mu.Lock()
+		keys = s.keys
+		s.mu.Unlock()
+
+		if k := find(keys); k != nil {
+			return k, nil
+		}
+	}
+
+	return key, nil
+}
+
+// canFetch returns true if the keys may be fetched without exceeding the
+// rate limit. The caller must hold s.mu.
+func (s *KeySet) canFetch() bool {
+	if s.uri == "" {
+		return false
+	}
+
+	if s.inflight != nil || s.lastFetch.IsZero() {
+		return true
+	}
+
+	interval := s.RefreshInterval
+	if interval == 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	return !timeNow().Before(s.lastFetch.Add(interval))
+}
+
+// fetchTimeout bounds a fetch, which isn't canceled along with the context of
+// the lookup which started it.
+var fetchTimeout = 30 * time.Second
+
+// refresh fetches the keys, or waits for the in-flight fetch to complete.
+//
+// The fetch is shared by every concurrent lookup, so it's made with a context
+// which isn't canceled along with ctx. Canceling ctx only stops waiting for it.
+func (s *KeySet) refresh(ctx context.Context) error {
+	s.mu.Lock()
+
+	c := s.inflight
+	if c == nil {
+		c = &fetchCall{done: make(chan struct{})}
+		s.inflight = c
+
+		go s.doFetch(context.WithoutCancel(ctx), c)
+	}
+
+	s.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// doFetch fetches the keys on behalf of the in-flight fetch c.
+func (s *KeySet) doFetch(ctx context.Context, c *fetchCall) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	keys, expiry, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys, s.expiry = keys, expiry
+	}
+	s.lastFetch = timeNow()
+	s.inflight = nil
+	s.mu.Unlock()
+
+	c.err = err
+	close(c.done)
+}
+
+// Verify verifies the signature of the JWS in compact serialization token
+// using the key identified by its header, and returns the payload. The
+// token's algorithm must be one of algs, or when algs is empty any of the
+// supported asymmetric algorithms. The "none" and HMAC algorithms are always
+// rejected.
+//
+// The provided context optionally controls which HTTP client is used. See the
+// oauth2.HTTPClient variable.
+func (s *KeySet) Verify(ctx context.Context, token string, algs ...string) ([]byte, error) {
+	header, err := jws.DecodeHeader(token)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: malformed token: %w", err)
+	}
+
+	if !allowed(header.Algorithm, algs) {
+		return nil, fmt.Errorf("jwks: token is signed with unsupported algorithm %q", header.Algorithm)
+	}
+
+	key, err := s.Key(ctx, header.KeyID, header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := jws.VerifyWithKey(token, header.Algorithm, key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	return payload, nil
+}
+
+func (s *KeySet) fetch(ctx context.Context) ([]*Key, time.Time, error) {
+	req, err := http.NewRequest("GET", s.uri, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	req.Header.Set("Accept", "application/json, application/jwk-set+json")
+
+	r, err := internal.Observe(ctx, internal.EndpointKindJWKS, "", func(ctx context.Context) (*http.Response, error) {
+		return internal.ContextClient(ctx).Do(req.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwks: cannot fetch key set: %w", err)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body.Close()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwks: cannot fetch key set: %w", err)
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return nil, time.Time{}, fmt.Errorf("jwks: cannot fetch key set: %v", r.Status)
+	}
+
+	keys, err := Parse(body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return keys, s.expiryFromHeader(r.Header), nil
+}
+
+// expiryFromHeader returns when the response with header expires according to
+// the Cache-Control max-age, or the Expires header.
+func (s *KeySet) expiryFromHeader(header http.Header) time.Time {
+	now := timeNow()
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return now
+		case "max-age":
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+
+			age, _ := strconv.Atoi(header.Get("Age"))
+
+			return now.Add(time.Duration(seconds-age) * time.Second)
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			return t
+		}
+
+		// An invalid Expires value represents a time in the past.
+		return now
+	}
+
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	return now.Add(ttl)
+}
+
+func findKey(keys []*Key, kid, alg string) *Key {
+	for _, key := range keys {
+		if kid != "" && key.KeyID != kid {
+			continue
+		}
+
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		if !compatible(key.Key, alg) {
+			continue
+		}
+
+		return key
+	}
+
+	return nil
+}
+
+func findEncryptionKey(keys []*Key, kid string) *Key {
+	for _, key := range keys {
+		if kid != "" && key.KeyID != kid {
+			continue
+		}
+
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, *ecdh.PublicKey:
+			return key
+		}
+	}
+
+	return nil
+}
+
+// compatible returns true if key can verify signatures of the algorithm alg.
+func compatible(key crypto.PublicKey, alg string) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case jws.RS256, jws.RS384, jws.RS512, jws.PS256, jws.PS384, jws.PS512:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		expected, err := jws.AlgorithmForKey(k)
+		return err == nil && expected == alg
+	case ed25519.PublicKey:
+		return alg == jws.EdDSA
+	}
+
+	return false
+}
+
+func allowed(alg string, algs []string) bool {
+	switch alg {
+	case "", "none", jws.HS256, jws.HS384, jws.HS512:
+		return false
+	}
+
+	if len(algs) == 0 {
+		return true
+	}
+
+	for _, a := range algs {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
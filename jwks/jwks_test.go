@@ -0,0 +1,263 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestKeySetVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		jwk, _ := jws.PublicJWK(pub)
+		jwk["kid"] = "key1"
+		jwk["use"] = "sig"
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk, map[string]any{"kty": "unknown"}}})
+	}))
+	defer ts.Close()
+
+	signer, alg, err := jws.NewSigner("", priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg, "kid": "key1"}, map[string]any{"sub": "abc"}, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := NewKeySet(ts.URL)
+	for i := 0; i < 2; i++ {
+		payload, err := ks.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(payload) != `{"sub":"abc"}` {
+			t.Errorf("Verify = %s; want {\"sub\":\"abc\"}", payload)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("key set fetched %d times; want 1", fetches)
+	}
+
+	if _, err = ks.Verify(context.Background(), token, jws.RS256); err == nil {
+		t.Error("Verify with disallowed algorithm = nil error; want error")
+	}
+
+	unknown, _ := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg, "kid": "key2"}, map[string]any{"sub": "abc"}, signer)
+	if _, err = ks.Verify(context.Background(), unknown); err == nil {
+		t.Error("Verify with unknown kid = nil error; want error")
+	}
+	if fetches != 1 {
+		t.Errorf("key set fetched %d times within the refresh interval; want 1", fetches)
+	}
+
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Now().Add(2 * DefaultRefreshInterval) }
+
+	if _, err = ks.Verify(context.Background(), unknown); err == nil {
+		t.Error("Verify with unknown kid = nil error; want error")
+	}
+	if fetches != 2 {
+		t.Errorf("key set fetched %d times; want 2", fetches)
+	}
+
+	hmac, _, _ := jws.NewHMACSigner(jws.HS256, make([]byte, 32))
+	hs, _ := jws.EncodeClaimsWithSigner(map[string]any{"alg": jws.HS256, "kid": "key1"}, map[string]any{"sub": "abc"}, hmac)
+	if _, err = ks.Verify(context.Background(), hs); err == nil {
+		t.Error("Verify with HS256 = nil error; want error")
+	}
+}
+
+func TestKeySetCaching(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		jwk, _ := jws.PublicJWK(pub)
+		jwk["kid"] = "key1"
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Header().Set("Age", "100")
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	}))
+	defer ts.Close()
+
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	ks := NewKeySet(ts.URL)
+
+	// Concurrent lookups share a single fetch.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ks.Key(context.Background(), "key1", jws.EdDSA); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("key set fetched %d times; want 1", fetches)
+	}
+
+	// The keys are cached for the max-age less the age.
+	now = now.Add(199 * time.Second)
+	if _, err = ks.Key(context.Background(), "key1", jws.EdDSA); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 {
+		t.Errorf("key set fetched %d times before expiry; want 1", fetches)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, err = ks.Key(context.Background(), "key1", jws.EdDSA); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Errorf("key set fetched %d times after expiry; want 2", fetches)
+	}
+}
+
+func TestKeySetCanceledLookup(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches int32
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+		}
+		<-release
+		jwk, _ := jws.PublicJWK(pub)
+		jwk["kid"] = "key1"
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	}))
+	defer ts.Close()
+
+	ks := NewKeySet(ts.URL)
+
+	// Canceling the lookup which started the fetch doesn't fail the lookups
+	// waiting for it.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := ks.Key(ctx, "key1", jws.EdDSA)
+		errc <- err
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ks.Key(context.Background(), "key1", jws.EdDSA)
+		done <- err
+	}()
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("canceled lookup error = %v; want %v", err, context.Canceled)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("waiting lookup error = %v; want nil", err)
+	}
+	if fetches != 1 {
+		t.Errorf("key set fetched %d times; want 1", fetches)
+	}
+}
+
+func TestExpiryFromHeader(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	tests := []struct {
+		header http.Header
+		want   time.Time
+	}{
+		{http.Header{}, now.Add(DefaultTTL)},
+		{http.Header{"Cache-Control": {"max-age=60"}}, now.Add(time.Minute)},
+		{http.Header{"Cache-Control": {"no-cache"}}, now},
+		{http.Header{"Expires": {"Mon, 01 Jan 2024 01:00:00 GMT"}}, now.Add(time.Hour)},
+		{http.Header{"Expires": {"0"}}, now},
+	}
+	for _, tt := range tests {
+		if got := (&KeySet{}).expiryFromHeader(tt.header); !got.Equal(tt.want) {
+			t.Errorf("expiryFromHeader(%v) = %v; want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseX5C(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, _ := jws.PublicJWK(key.Public())
+	jwk["kid"] = "key1"
+	jwk["x5c"] = []string{base64.StdEncoding.EncodeToString(der)}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	mismatched, _ := jws.PublicJWK(other.Public())
+	mismatched["kid"] = "key2"
+	mismatched["x5c"] = jwk["x5c"]
+
+	data, _ := json.Marshal(map[string]any{"keys": []any{jwk, mismatched}})
+	keys, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].KeyID != "key1" {
+		t.Fatalf("Parse returned %d keys; want only key1", len(keys))
+	}
+	if len(keys[0].Certificates) != 1 || !keys[0].Certificates[0].Equal(mustParseCertificate(t, der)) {
+		t.Errorf("Unexpected certificates %v", keys[0].Certificates)
+	}
+}
+
+func mustParseCertificate(t *testing.T, der []byte) *x509.Certificate {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
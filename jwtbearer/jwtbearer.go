@@ -0,0 +1,267 @@
+// Package jwtbearer implements the OAuth 2.0 JWT bearer authorization grant
+// where a signed JWT assertion is exchanged for an access token.
+//
+// See https://datatracker.ietf.org/doc/html/rfc7523#section-2.1
+package jwtbearer // import "authelia.com/client/oauth2/jwtbearer"
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/internal"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// GrantType is the RFC 7523 JWT bearer grant type.
+const GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// DefaultExpires is the lifetime of the assertion when Config.Expires is zero.
+const DefaultExpires = time.Hour
+
+// Config describes an RFC 7523 JWT bearer grant, with the claims of the
+// assertion and the server's token endpoint.
+type Config struct {
+	// PrivateKey signs the assertion. The authorization server must trust its
+	// public key for the Issuer, such as through the issuer's JWKS.
+	PrivateKey crypto.Signer
+
+	// PrivateKeyID optionally specifies the 'kid' header of the assertion.
+	PrivateKeyID string
+
+	// Algorithm optionally specifies the JWS algorithm used to sign the
+	// assertion, such as RS256, PS256, ES256 or EdDSA. If empty, it's derived
+	// from the PrivateKey.
+	Algorithm string
+
+	// Headers optionally specifies additional JOSE headers of the assertion,
+	// such as 'x5t' or 'x5c'. The 'alg' header can't be overridden.
+	Headers map[string]any
+
+	// Issuer is the 'iss' claim of the assertion.
+	Issuer string
+
+	// Subject is the 'sub' claim of the assertion. If empty, the Issuer is
+	// used.
+	Subject string
+
+	// Audience optionally specifies the 'aud' claim of the assertion. If
+	// empty, the TokenURL is used.
+	Audience string
+
+	// Expires optionally specifies how long the assertion is valid for. If
+	// zero, DefaultExpires is used.
+	Expires time.Duration
+
+	// NotBefore optionally returns the 'nbf' claim of each assertion from the
+	// time it's issued, such as that time less an allowance for clock skew. If
+	// nil, the claim is omitted.
+	NotBefore func(issued time.Time) time.Time
+
+	// JWTID optionally returns the 'jti' claim of each assertion. If nil, a
+	// random value is used.
+	JWTID func() (string, error)
+
+	// PrivateClaims optionally specifies additional claims of the assertion.
+	// The claims above take precedence over them.
+	PrivateClaims map[string]any
+
+	// ClientID is the application's optional ID, used when the server
+	// requires client authentication with the grant.
+	ClientID string
+
+	// ClientSecret is the application's optional secret.
+	ClientSecret string
+
+	// TokenURL is the server's token endpoint URL.
+	TokenURL string
+
+	// Scopes specifies optional requested permissions.
+	Scopes []string
+
+	// EndpointParams specifies additional parameters for requests to the token endpoint.
+	EndpointParams url.Values
+
+	// AuthStyle optionally specifies how the endpoint wants the client ID and
+	// client secret sent. The zero value means to auto-detect, and when there
+	// is no ClientID the client isn't authenticated.
+	AuthStyle oauth2.AuthStyle
+
+	// authStyleCache caches which auth style to use when AuthStyle is the
+	// zero value (AuthStyleAutoDetect).
+	authStyleCache internal.LazyAuthStyleCache
+}
+
+// Assertion returns a newly signed assertion.
+func (c *Config) Assertion() (string, error) {
+	if c.PrivateKey == nil {
+		return "", errors.New("oauth2: jwtbearer: no private key was provided")
+	}
+
+	signer, alg, err := jws.NewSigner(c.Algorithm, c.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := c.jwtID()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]any{}
+
+	for k, v := range c.Headers {
+		header[k] = v
+	}
+
+	header["alg"] = alg
+
+	if _, ok := header["typ"]; !ok {
+		header["typ"] = "JWT"
+	}
+
+	if c.PrivateKeyID != "" {
+		header["kid"] = c.PrivateKeyID
+	}
+
+	subject := c.Subject
+	if subject == "" {
+		subject = c.Issuer
+	}
+
+	audience := c.Audience
+	if audience == "" {
+		audience = c.TokenURL
+	}
+
+	expires := c.Expires
+	if expires == 0 {
+		expires = DefaultExpires
+	}
+
+	now := time.Now()
+
+	claims := map[string]any{}
+
+	for k, v := range c.PrivateClaims {
+		claims[k] = v
+	}
+
+	claims["iss"] = c.Issuer
+	claims["sub"] = subject
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(expires).Unix()
+	claims["jti"] = jti
+
+	if c.NotBefore != nil {
+		claims["nbf"] = c.NotBefore(now).Unix()
+	}
+
+	return jws.EncodeClaimsWithSigner(header, claims, signer)
+}
+
+func (c *Config) jwtID() (string, error) {
+	if c.JWTID != nil {
+		return c.JWTID()
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Token uses a newly signed assertion to retrieve a token.
+//
+// The provided context optionally controls which HTTP client is used. See the oauth2.HTTPClient variable.
+func (c *Config) Token(ctx context.Context) (*oauth2.Token, error) {
+	return c.TokenSource(ctx).Token()
+}
+
+// Client returns an HTTP client wrapping the context's HTTP transport and
+// adding Authorization headers with tokens obtained from c.
+//
+// The returned Client and its Transport should not be modified.
+func (c *Config) Client(ctx context.Context) *http.Client {
+	return oauth2.NewClient(ctx, c.TokenSource(ctx))
+}
+
+// TokenSource returns a TokenSource that returns t until t expires,
+// automatically retrieving a new token with a newly signed assertion as
+// necessary.
+//
+// Most users will use Config.Client instead.
+func (c *Config) TokenSource(ctx context.Context) oauth2.TokenSource {
+	source := &tokenSource{
+		ctx:  ctx,
+		conf: c,
+	}
+	return oauth2.ReuseTokenSource(nil, source)
+}
+
+type tokenSource struct {
+	ctx  context.Context
+	conf *Config
+}
+
+// Token retrieves a token with a newly signed assertion.
+func (c *tokenSource) Token() (*oauth2.Token, error) {
+	assertion, err := c.conf.Assertion()
+	if err != nil {
+		return nil, err
+	}
+
+	v := url.Values{
+		"grant_type": {GrantType},
+		"assertion":  {assertion},
+	}
+	if len(c.conf.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.conf.Scopes, " "))
+	}
+	for k, p := range c.conf.EndpointParams {
+		if _, ok := v[k]; ok {
+			return nil, fmt.Errorf("oauth2: cannot overwrite parameter %q", k)
+		}
+		v[k] = p
+	}
+
+	authStyle := internal.AuthStyle(c.conf.AuthStyle)
+	if authStyle == internal.AuthStyleUnknown && c.conf.ClientID == "" {
+		// The assertion alone authorizes the grant, so there is nothing to probe.
+		authStyle = internal.AuthStyleInParams
+	}
+
+	auth := &internal.ClientAuth{
+		ClientID:     c.conf.ClientID,
+		ClientSecret: c.conf.ClientSecret,
+		Audience:     c.conf.TokenURL,
+	}
+
+	tk, err := internal.RetrieveToken(c.ctx, auth, c.conf.TokenURL, v, authStyle, c.conf.authStyleCache.Get())
+	if err != nil {
+		if rErr, ok := err.(*internal.RetrieveError); ok {
+			return nil, &oauth2.RetrieveError{BaseError: (*oauth2.BaseError)(rErr)}
+		}
+		return nil, err
+	}
+	t := &oauth2.Token{
+		AccessToken:     tk.AccessToken,
+		TokenType:       tk.TokenType,
+		RefreshToken:    tk.RefreshToken,
+		IDToken:         tk.IDToken,
+		IssuedTokenType: tk.IssuedTokenType,
+		Expiry:          tk.Expiry,
+	}
+	return t.WithExtra(tk.Raw), nil
+}
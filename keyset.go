@@ -0,0 +1,37 @@
+package oauth2
+
+import (
+	"sync/atomic"
+
+	"authelia.com/client/oauth2/jwks"
+)
+
+// lazyKeySet lazily creates the jwks.KeySet of a Config. Like the
+// internal.LazyAuthStyleCache it uses an atomic.Value so that a Config may
+// still be copied by value.
+type lazyKeySet struct {
+	v atomic.Value // of *jwks.KeySet
+}
+
+func (l *lazyKeySet) get(uri string) *jwks.KeySet {
+	if ks, ok := l.v.Load().(*jwks.KeySet); ok && ks.URL() == uri {
+		return ks
+	}
+
+	ks := jwks.NewKeySet(uri)
+	l.v.Store(ks)
+
+	return ks
+}
+
+// KeySet returns the key set of the Endpoint.JWKSURL used to verify the JWTs
+// issued by the authorization server. The key set is created once and shared
+// by every use of the Config, so the keys are cached and rate limited across
+// all of them.
+func (c *Config) KeySet() (*jwks.KeySet, error) {
+	if c.Endpoint.JWKSURL == "" {
+		return nil, jwks.ErrNoKeySet
+	}
+
+	return c.keySetCache.get(c.Endpoint.JWKSURL), nil
+}
@@ -0,0 +1,52 @@
+package oauth2
+
+// MTLSEndpointAliases are the alternative endpoint URLs an authorization server
+// advertises for clients which use mutual-TLS, as described in RFC 8705
+// section 5. Empty values fall back to the respective Endpoint URL.
+type MTLSEndpointAliases struct {
+	DeviceAuthURL      string
+	PushedAuthURL      string
+	BackchannelAuthURL string
+	TokenURL           string
+	IntrospectionURL   string
+	RevocationURL      string
+	UserinfoURL        string
+}
+
+// endpointURL returns alias if the Config uses a client certificate and the
+// alias is configured, otherwise it returns uri.
+func (c *Config) endpointURL(uri, alias string) string {
+	if c.ClientCertificate != nil && alias != "" {
+		return alias
+	}
+
+	return uri
+}
+
+func (c *Config) deviceAuthURL() string {
+	return c.endpointURL(c.Endpoint.DeviceAuthURL, c.Endpoint.MTLSEndpointAliases.DeviceAuthURL)
+}
+
+func (c *Config) pushedAuthURL() string {
+	return c.endpointURL(c.Endpoint.PushedAuthURL, c.Endpoint.MTLSEndpointAliases.PushedAuthURL)
+}
+
+func (c *Config) backchannelAuthURL() string {
+	return c.endpointURL(c.Endpoint.BackchannelAuthURL, c.Endpoint.MTLSEndpointAliases.BackchannelAuthURL)
+}
+
+func (c *Config) tokenURL() string {
+	return c.endpointURL(c.Endpoint.TokenURL, c.Endpoint.MTLSEndpointAliases.TokenURL)
+}
+
+func (c *Config) revocationURL() string {
+	return c.endpointURL(c.Endpoint.RevocationURL, c.Endpoint.MTLSEndpointAliases.RevocationURL)
+}
+
+func (c *Config) introspectionURL() string {
+	return c.endpointURL(c.Endpoint.IntrospectionURL, c.Endpoint.MTLSEndpointAliases.IntrospectionURL)
+}
+
+func (c *Config) userinfoURL() string {
+	return c.endpointURL(c.Endpoint.UserinfoURL, c.Endpoint.MTLSEndpointAliases.UserinfoURL)
+}
@@ -0,0 +1,93 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSelfSignedCertificate(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "CLIENT_ID"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestMutualTLSClientAuth(t *testing.T) {
+	cert := newSelfSignedCertificate(t)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) != 1 {
+			t.Errorf("Unexpected peer certificates for %q", r.URL)
+		} else if got := r.TLS.PeerCertificates[0].Subject.CommonName; got != "CLIENT_ID" {
+			t.Errorf("Unexpected peer certificate %q", got)
+		}
+		switch r.URL.Path {
+		case "/mtls/token":
+			if got := r.Header.Get("Authorization"); got != "" {
+				t.Errorf("Unexpected authorization header %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "client_id=CLIENT_ID&code=exchange-code&grant_type=authorization_code&redirect_uri=REDIRECT_URL" {
+				t.Errorf("Unexpected exchange payload; got %q", body)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "ACCESS_TOKEN", "token_type": "bearer"}`))
+		case "/resource":
+			if got, want := r.Header.Get("Authorization"), "Bearer ACCESS_TOKEN"; got != want {
+				t.Errorf("Authorization header = %q; want %q", got, want)
+			}
+		default:
+			t.Errorf("Unexpected request URL %q", r.URL)
+		}
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.ClientSecret = ""
+	conf.ClientCertificate = cert
+	conf.Endpoint.AuthStyle = AuthStyleSelfSignedTLSClientAuth
+	conf.Endpoint.MTLSEndpointAliases.TokenURL = ts.URL + "/mtls/token"
+
+	ctx := context.WithValue(context.Background(), HTTPClient, ts.Client())
+	tok, err := conf.Exchange(ctx, "exchange-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := conf.Client(ctx, tok).Get(ts.URL + "/resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestMutualTLSClientAuth_NoCertificate(t *testing.T) {
+	conf := newConf("https://example.com")
+	conf.Endpoint.AuthStyle = AuthStyleTLSClientAuth
+	if _, err := conf.Exchange(context.Background(), "exchange-code"); err == nil {
+		t.Error("Exchange = nil error; want error for missing client certificate")
+	}
+}
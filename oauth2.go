@@ -11,6 +11,8 @@ package oauth2 // import "authelia.com/client/oauth2"
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/tls"
 	"errors"
 	"net/http"
 	"net/url"
@@ -32,6 +34,55 @@ type Config struct {
 	// ClientSecret is the application's secret.
 	ClientSecret string
 
+	// PrivateKey is the application's private key. It is used to sign the
+	// client assertion when Endpoint.AuthStyle is AuthStylePrivateKeyJWT.
+	// Any crypto.Signer may be used, which allows keys held in an HSM or KMS.
+	PrivateKey crypto.Signer
+
+	// PrivateKeyID optionally specifies the 'kid' header of the client
+	// assertion signed with PrivateKey.
+	PrivateKeyID string
+
+	// ClientAssertionAlgorithm optionally specifies the JWS algorithm used to
+	// sign client assertions. If empty, it's derived from the PrivateKey, or
+	// HS256 is used for AuthStyleClientSecretJWT. The ClientSecret must
+	// then be at least as long as the output of the algorithm's hash, such as
+	// 32 bytes for HS256.
+	ClientAssertionAlgorithm string
+
+	// ClientCertificate is the application's optional TLS client certificate.
+	// When set it's presented to the authorization server's endpoints, which
+	// then prefer the Endpoint.MTLSEndpointAliases, and to resource servers
+	// through the Client so certificate-bound access tokens may be used as
+	// described in RFC 8705. It's required when Endpoint.AuthStyle is
+	// AuthStyleTLSClientAuth or AuthStyleSelfSignedTLSClientAuth. The HTTP
+	// client's transport must present it itself unless it's an
+	// *http.Transport.
+	ClientCertificate *tls.Certificate
+
+	// DPoP optionally sender-constrains tokens to the client's key as
+	// described in RFC 9449. When set, proofs are sent with token requests
+	// and the Client presents DPoP bound tokens with a fresh proof for each
+	// request.
+	DPoP *DPoP
+
+	// DecryptionKey is the application's optional private key used to decrypt
+	// encrypted responses such as UserInfo responses. *rsa.PrivateKey,
+	// *ecdsa.PrivateKey and *ecdh.PrivateKey keys are supported.
+	DecryptionKey crypto.PrivateKey
+
+	// Retry optionally retries requests to the authorization server which
+	// fail with a transient error. If nil, each request is attempted once.
+	Retry *RetryPolicy
+
+	// Hooks are optionally called around the requests made with the Config,
+	// taking precedence over the hooks of the context. See ContextWithHooks.
+	Hooks Hooks
+
+	// RequestObject optionally sends the authorization request parameters in
+	// a signed request object as described in RFC 9101.
+	RequestObject *RequestObject
+
 	// Endpoint contains the resource server's token endpoint
 	// URLs. These are constants specific to each server and are
 	// often available via site-specific packages, such as
@@ -48,6 +99,9 @@ type Config struct {
 	// authStyleCache caches which auth style to use when Endpoint.AuthStyle is
 	// the zero value (AuthStyleAutoDetect).
 	authStyleCache internal.LazyAuthStyleCache
+
+	// keySetCache caches the key set retrieved from Endpoint.JWKSURL.
+	keySetCache lazyKeySet
 }
 
 // A TokenSource is anything that can return a token.
@@ -61,14 +115,28 @@ type TokenSource interface {
 // Endpoint represents an OAuth 2.0 provider's authorization and token
 // endpoint URLs.
 type Endpoint struct {
-	AuthURL          string
-	DeviceAuthURL    string
-	PushedAuthURL    string
-	TokenURL         string
-	IntrospectionURL string
-	RevocationURL    string
-	UserinfoURL      string
-	JWKSURL          string
+	// Issuer is the optional issuer identifier of the authorization server.
+	// When set, it's compared to the 'iss' claim of the JWTs it issues.
+	Issuer string
+
+	AuthURL            string
+	DeviceAuthURL      string
+	PushedAuthURL      string
+	BackchannelAuthURL string
+	TokenURL           string
+	IntrospectionURL   string
+	RevocationURL      string
+	UserinfoURL        string
+	JWKSURL            string
+
+	// IssuerParameterSupported indicates the authorization server includes
+	// the RFC 9207 'iss' parameter in its authorization responses, which
+	// makes the parameter required by ParseAuthorizationResponse.
+	IssuerParameterSupported bool
+
+	// MTLSEndpointAliases optionally specifies the endpoint URLs to use
+	// instead of the above when the Config has a ClientCertificate.
+	MTLSEndpointAliases MTLSEndpointAliases
 
 	// AuthStyle optionally specifies how the endpoint wants the
 	// client ID & client secret sent. The zero value means to
@@ -97,11 +165,45 @@ const (
 	// This is also known as 'client_secret_basic'.
 	AuthStyleInHeader AuthStyle = 2
 
+	// AuthStylePrivateKeyJWT sends the "client_id" and a "client_assertion"
+	// JWT signed with the client's private key in the POST body, as described
+	// in RFC 7523 section 2.2. This is also known as 'private_key_jwt'.
+	AuthStylePrivateKeyJWT AuthStyle = 3
+
+	// AuthStyleClientSecretJWT sends the "client_id" and a "client_assertion"
+	// JWT signed with an HMAC of the client secret in the POST body, as
+	// described in RFC 7523 section 2.2. The client secret itself is never
+	// sent. This is also known as 'client_secret_jwt'.
+	AuthStyleClientSecretJWT AuthStyle = 4
+
+	// AuthStyleTLSClientAuth sends the "client_id" in the POST body and
+	// authenticates using a PKI bound TLS client certificate, as described
+	// in RFC 8705 section 2.1. This is also known as 'tls_client_auth'.
+	AuthStyleTLSClientAuth AuthStyle = 5
+
+	// AuthStyleSelfSignedTLSClientAuth sends the "client_id" in the POST body
+	// and authenticates using a self-signed TLS client certificate, as
+	// described in RFC 8705 section 2.2. This is also known as
+	// 'self_signed_tls_client_auth'.
+	AuthStyleSelfSignedTLSClientAuth AuthStyle = 6
+
 	// ClientSecretBasic is an alias for AuthStyleInHeader.
 	ClientSecretBasic = AuthStyleInHeader
 
 	// ClientSecretPost is an alias for AuthStyleInParams.
 	ClientSecretPost = AuthStyleInParams
+
+	// PrivateKeyJWT is an alias for AuthStylePrivateKeyJWT.
+	PrivateKeyJWT = AuthStylePrivateKeyJWT
+
+	// ClientSecretJWT is an alias for AuthStyleClientSecretJWT.
+	ClientSecretJWT = AuthStyleClientSecretJWT
+
+	// TLSClientAuth is an alias for AuthStyleTLSClientAuth.
+	TLSClientAuth = AuthStyleTLSClientAuth
+
+	// SelfSignedTLSClientAuth is an alias for AuthStyleSelfSignedTLSClientAuth.
+	SelfSignedTLSClientAuth = AuthStyleSelfSignedTLSClientAuth
 )
 
 var (
@@ -156,13 +258,40 @@ func SetAuthURLParam(key, value string) AuthCodeOption {
 // See https://datatracker.ietf.org/doc/html/rfc6749#section-10.12 (predating
 // PKCE), https://www.oauth.com/oauth2-servers/pkce/ and
 // https://www.ietf.org/archive/id/draft-ietf-oauth-v2-1-09.html#name-cross-site-request-forgery (describing both approaches)
+//
+// AuthCodeURL can only fail when the Config has a RequestObject, as the
+// parameters are then sent in a signed request object which may not be
+// created, in which case the empty string is returned without the error.
+// Configs with a RequestObject should use ParsedAuthCodeURL instead, which
+// returns the error.
 func (c *Config) AuthCodeURL(state string, opts ...AuthCodeOption) string {
+	v, err := c.requestObjectValues(context.Background(), c.getAuthCodeValues(state, opts...))
+	if err != nil {
+		return ""
+	}
+
+	return c.authCodeURL(v)
+}
+
+// ParsedAuthCodeURL is the same as AuthCodeURL just it wraps the result in url.Parse.
+// It also returns the error of creating the request object of a Config with a
+// RequestObject.
+func (c *Config) ParsedAuthCodeURL(state string, opts ...AuthCodeOption) (authURL *url.URL, err error) {
+	v, err := c.requestObjectValues(context.Background(), c.getAuthCodeValues(state, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return url.Parse(c.authCodeURL(v))
+}
+
+// authCodeURL returns the URL of the authorization endpoint with the
+// parameters v.
+func (c *Config) authCodeURL(v url.Values) string {
 	buf := &bytes.Buffer{}
 
 	buf.WriteString(c.Endpoint.AuthURL)
 
-	v := c.getAuthCodeValues(state, opts...)
-
 	if strings.Contains(c.Endpoint.AuthURL, "?") {
 		buf.WriteByte('&')
 	} else {
@@ -174,13 +303,6 @@ func (c *Config) AuthCodeURL(state string, opts ...AuthCodeOption) string {
 	return buf.String()
 }
 
-// ParsedAuthCodeURL is the same as AuthCodeURL just it wraps the result in url.Parse.
-func (c *Config) ParsedAuthCodeURL(state string, opts ...AuthCodeOption) (authURL *url.URL, err error) {
-	raw := c.AuthCodeURL(state, opts...)
-
-	return url.Parse(raw)
-}
-
 func (c *Config) getAuthCodeValues(state string, opts ...AuthCodeOption) url.Values {
 	v := url.Values{
 		"response_type": {"code"},
@@ -232,9 +354,9 @@ func (c *Config) PasswordCredentialsToken(ctx context.Context, username, passwor
 //
 // The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
 //
-// The code will be in the *http.Request.FormValue("code"). Before
-// calling Exchange, be sure to validate FormValue("state") if you are
-// using it to protect against CSRF attacks.
+// The code is obtained from the request to the RedirectURL with
+// ParseAuthorizationResponse, which also validates the state to protect
+// against CSRF attacks.
 //
 // If using PKCE to protect against CSRF attacks, opts should include a
 // VerifierOption.
@@ -270,8 +392,12 @@ func (c *Config) Token(ctx context.Context, opts ...AuthCodeOption) (*Token, err
 // The token will auto-refresh as necessary. The underlying
 // HTTP transport will be obtained using the provided context.
 // The returned client and its Transport should not be modified.
+//
+// If c has a ClientCertificate it's also presented to the resource server,
+// and if c has a DPoP it's used to present DPoP bound tokens.
 func (c *Config) Client(ctx context.Context, t *Token) *http.Client {
-	return NewClient(ctx, c.TokenSource(ctx, t))
+	cctx := internal.ContextWithCertificate(ctx, c.ClientCertificate)
+	return newClient(cctx, c.TokenSource(ctx, t), c.DPoP)
 }
 
 // TokenSource returns a TokenSource that returns t until t expires,
@@ -289,15 +415,46 @@ func (c *Config) TokenSource(ctx context.Context, t *Token) TokenSource {
 	return &reuseTokenSource{
 		t:   t,
 		new: tkr,
+		ctx: c.hooksContext(ctx),
 	}
 }
 
+// clientAuth returns the credentials used to authenticate c to the
+// authorization server's endpoints.
+func (c *Config) clientAuth() *internal.ClientAuth {
+	auth := &internal.ClientAuth{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		PrivateKey:   c.PrivateKey,
+		PrivateKeyID: c.PrivateKeyID,
+		Algorithm:    c.ClientAssertionAlgorithm,
+		Audience:     c.Endpoint.TokenURL,
+		Certificate:  c.ClientCertificate,
+	}
+	if c.DPoP != nil {
+		auth.DPoP = c.DPoP
+	}
+	if c.Retry != nil {
+		auth.Retry = (*internal.RetryPolicy)(c.Retry)
+	}
+	if c.Hooks != nil {
+		auth.Hooks = internalHooks(c.Hooks)
+	}
+	return auth
+}
+
 // tokenRefresher is a TokenSource that makes "grant_type"=="refresh_token"
 // HTTP requests to renew a token using a RefreshToken.
 type tokenRefresher struct {
 	ctx          context.Context // used to get HTTP requests
 	conf         *Config
 	refreshToken string
+
+	// store optionally persists the token with the key, in which case the
+	// token is loaded from it before each refresh and refreshToken isn't
+	// used.
+	store TokenStore
+	key   string
 }
 
 // WARNING: Token is not safe for concurrent access, as it
@@ -305,6 +462,10 @@ type tokenRefresher struct {
 // Within this package, it is used by reuseTokenSource which
 // synchronizes calls to this method with its own mutex.
 func (tf *tokenRefresher) Token() (*Token, error) {
+	if tf.store != nil {
+		return tf.storedToken()
+	}
+
 	if tf.refreshToken == "" {
 		return nil, errors.New("oauth2: token expired and refresh token is not set")
 	}
@@ -330,10 +491,20 @@ func (tf *tokenRefresher) Token() (*Token, error) {
 type reuseTokenSource struct {
 	new TokenSource // called when t is expired.
 
-	mu sync.Mutex // guards t
+	mu sync.Mutex // guards t, retryAt and backoff
 	t  *Token
 
 	expiryDelta time.Duration
+
+	// ctx optionally has the hooks notified when the token is returned from
+	// the cache.
+	ctx context.Context
+
+	// stale optionally allows t to be returned after it expires when it
+	// can't be refreshed, until retryAt while backing off by backoff.
+	stale   *StaleOptions
+	retryAt time.Time
+	backoff time.Duration
 }
 
 // Token returns the current token if it's still valid, else will
@@ -343,17 +514,38 @@ func (s *reuseTokenSource) Token() (*Token, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.t.Valid() {
+		if s.ctx != nil {
+			internal.ObserveCached(s.ctx, EndpointKindToken, "")
+		}
 		return s.t, nil
 	}
+	if s.stale != nil && s.stale.usable(s.t) && timeNow().Before(s.retryAt) {
+		return s.t.asStale(), nil
+	}
 	t, err := s.new.Token()
 	if err != nil {
+		if s.stale != nil && s.stale.usable(s.t) && isTransient(err) {
+			s.backoff = s.stale.nextBackoff(s.backoff)
+			s.retryAt = timeNow().Add(s.backoff)
+			return s.t.asStale(), nil
+		}
 		return nil, err
 	}
 	t.expiryDelta = s.expiryDelta
 	s.t = t
+	s.backoff = 0
+	s.retryAt = time.Time{}
 	return t, nil
 }
 
+func (s *reuseTokenSource) invalidate(t *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t != nil && s.t.AccessToken == t.AccessToken {
+		s.t = nil
+	}
+}
+
 // StaticTokenSource returns a TokenSource that always returns the same token.
 // Because the provided token t is never refreshed, StaticTokenSource is only
 // useful for tokens that never expire.
@@ -385,6 +577,10 @@ var HTTPClient internal.ContextKey
 // using the provided context. This exists to support related OAuth2
 // packages.
 func NewClient(ctx context.Context, src TokenSource) *http.Client {
+	return newClient(ctx, src, nil)
+}
+
+func newClient(ctx context.Context, src TokenSource, dpop *DPoP) *http.Client {
 	if src == nil {
 		return internal.ContextClient(ctx)
 	}
@@ -393,6 +589,7 @@ func NewClient(ctx context.Context, src TokenSource) *http.Client {
 		Transport: &Transport{
 			Base:   cc.Transport,
 			Source: ReuseTokenSource(nil, src),
+			DPoP:   dpop,
 		},
 		CheckRedirect: cc.CheckRedirect,
 		Jar:           cc.Jar,
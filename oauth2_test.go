@@ -135,6 +135,31 @@ func TestExchangeRequest(t *testing.T) {
 	}
 }
 
+func TestExchangeRequest_ClientSecretJWT(t *testing.T) {
+	var tokenURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkClientSecretJWT(t, r, tokenURL)
+		if got := r.FormValue("code"); got != "exchange-code" {
+			t.Errorf("Unexpected code %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "ACCESS_TOKEN", "token_type": "bearer"}`))
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.ClientSecret = clientSecretJWTSecret
+	conf.Endpoint.AuthStyle = AuthStyleClientSecretJWT
+	conf.ClientAssertionAlgorithm = "HS384"
+	tokenURL = conf.Endpoint.TokenURL
+	tok, err := conf.Exchange(context.Background(), "exchange-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "ACCESS_TOKEN" {
+		t.Errorf("Unexpected access token, %#v.", tok.AccessToken)
+	}
+}
+
 func TestExchangeRequest_CustomParam(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.String() != "/token" {
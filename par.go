@@ -12,17 +12,17 @@ import (
 // POST request to the configured Pushed Auth URL. In addition, it returns the *url.URL of the properly formatted AuthURL
 // for the PAR session provided the AuthURL Endpoint is configured.
 func (c *Config) PushedAuth(ctx context.Context, state string, opts ...AuthCodeOption) (authURL *url.URL, par *internal.PushedAuthResponse, err error) {
-	if c.Endpoint.PushedAuthURL == "" {
+	if c.pushedAuthURL() == "" {
 		return nil, nil, errors.New("endpoint missing PushedAuthURL")
 	}
 
 	var v url.Values
 
-	if authURL, v, err = c.getPushedAuthCodeValues(state, opts...); err != nil {
+	if authURL, v, err = c.getPushedAuthCodeValues(ctx, state, opts...); err != nil {
 		return nil, nil, err
 	}
 
-	if par, err = internal.RetrievePushedAuthResponse(ctx, c.ClientID, c.ClientSecret, c.Endpoint.PushedAuthURL, v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get()); err != nil {
+	if par, err = internal.RetrievePushedAuthResponse(ctx, c.clientAuth(), c.pushedAuthURL(), v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get()); err != nil {
 		var rErr *internal.RetrieveError
 
 		if errors.As(err, &rErr) {
@@ -42,7 +42,7 @@ func (c *Config) PushedAuth(ctx context.Context, state string, opts ...AuthCodeO
 	return authURL, par, nil
 }
 
-func (c *Config) getPushedAuthCodeValues(state string, opts ...AuthCodeOption) (authURL *url.URL, v url.Values, err error) {
+func (c *Config) getPushedAuthCodeValues(ctx context.Context, state string, opts ...AuthCodeOption) (authURL *url.URL, v url.Values, err error) {
 	if c.Endpoint.AuthURL != "" {
 		if authURL, err = url.ParseRequestURI(c.Endpoint.AuthURL); err != nil {
 			return nil, url.Values{}, fmt.Errorf("failed to parse AuthURL: %w", err)
@@ -56,7 +56,10 @@ func (c *Config) getPushedAuthCodeValues(state string, opts ...AuthCodeOption) (
 		v = url.Values{}
 	}
 
-	xv := c.getAuthCodeValues(state, opts...)
+	xv, err := c.requestObjectValues(ctx, c.getAuthCodeValues(state, opts...))
+	if err != nil {
+		return nil, url.Values{}, err
+	}
 
 	for key, value := range xv {
 		v[key] = value
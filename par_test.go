@@ -2,10 +2,15 @@ package oauth2
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +52,78 @@ func TestPushAuthRequest(t *testing.T) {
 		t.Errorf("Unexpected request_uri; got %s", got)
 	}
 }
+
+// clientSecretJWTSecret is a client secret long enough for HS384.
+const clientSecretJWTSecret = "0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// checkClientSecretJWT checks that r authenticates the client with an HS384
+// client assertion signed with clientSecretJWTSecret for the audience aud.
+func checkClientSecretJWT(t *testing.T, r *http.Request, aud string) {
+	t.Helper()
+	if got := r.Header.Get("Authorization"); got != "" {
+		t.Errorf("Unexpected authorization header %q", got)
+	}
+	if got := r.FormValue("client_secret"); got != "" {
+		t.Errorf("Unexpected client_secret %q", got)
+	}
+	if got, want := r.FormValue("client_assertion_type"), "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"; got != want {
+		t.Errorf("client_assertion_type = %q; want %q", got, want)
+	}
+	parts := strings.Split(r.FormValue("client_assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("Unexpected client_assertion %q", r.FormValue("client_assertion"))
+	}
+	mac := hmac.New(sha512.New384, []byte(clientSecretJWTSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("Unexpected client_assertion signature %q, want %q", parts[2], want)
+	}
+	var header, claims map[string]any
+	for i, v := range []any{&header, &claims} {
+		b, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(b, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if header["alg"] != "HS384" {
+		t.Errorf("Unexpected alg %v", header["alg"])
+	}
+	if claims["iss"] != "CLIENT_ID" || claims["sub"] != "CLIENT_ID" || claims["aud"] != aud {
+		t.Errorf("Unexpected claims %v", claims)
+	}
+	if jti, _ := claims["jti"].(string); jti == "" {
+		t.Errorf("Unexpected empty jti")
+	}
+}
+
+func TestPushAuthRequest_ClientSecretJWT(t *testing.T) {
+	var tokenURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkClientSecretJWT(t, r, tokenURL)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"request_uri": "urn:ietf:params:oauth:request_uri:6esc_11ACC5bwc014ltc14eY22c", "expires_in": 60}`))
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.ClientSecret = clientSecretJWTSecret
+	conf.Endpoint.AuthStyle = AuthStyleClientSecretJWT
+	conf.ClientAssertionAlgorithm = "HS384"
+	tokenURL = conf.Endpoint.TokenURL
+	if _, _, err := conf.PushedAuth(context.Background(), "state"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret must be at least as long as the output of the hash.
+	conf.ClientSecret = clientSecretJWTSecret[:47]
+	if _, _, err := conf.PushedAuth(context.Background(), "state"); err == nil {
+		t.Error("PushedAuth with a short secret = nil error; want error")
+	}
+}
+
 func TestPushAuthRequestErrorResponse(t *testing.T) {
 	type testCase struct {
 		name                string
@@ -0,0 +1,203 @@
+package oauth2
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"sync/atomic"
+)
+
+// redacted replaces the secrets when formatting and logging.
+const redacted = "REDACTED"
+
+// secretParams are the parameters of token responses and requests whose
+// values are redacted.
+var secretParams = map[string]bool{
+	"access_token":     true,
+	"refresh_token":    true,
+	"id_token":         true,
+	"client_secret":    true,
+	"client_assertion": true,
+	"assertion":        true,
+	"device_code":      true,
+	"subject_token":    true,
+	"actor_token":      true,
+	"code":             true,
+	"code_verifier":    true,
+}
+
+var revealSecrets atomic.Bool
+
+// RevealSecrets sets whether the tokens and secrets of the values of this
+// package, such as a Token's AccessToken or a Config's ClientSecret, are
+// revealed rather than redacted when they're formatted with the fmt package
+// or logged with the log/slog package. It's only intended for debugging, as
+// logs are rarely a safe place for secrets.
+func RevealSecrets(reveal bool) {
+	revealSecrets.Store(reveal)
+}
+
+// redact returns s, or a placeholder if s is a non-empty secret which isn't
+// revealed.
+func redact(s string) string {
+	if s == "" || revealSecrets.Load() {
+		return s
+	}
+
+	return redacted
+}
+
+// redactParams returns a copy of raw, the extra metadata of a Token, with the
+// values of secretParams redacted.
+func redactParams(raw any) any {
+	if revealSecrets.Load() {
+		return raw
+	}
+
+	switch raw := raw.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(raw))
+		for k, v := range raw {
+			if secretParams[k] {
+				v = redacted
+			}
+			m[k] = v
+		}
+		return m
+	case url.Values:
+		vals := make(url.Values, len(raw))
+		for k, v := range raw {
+			if secretParams[k] {
+				v = []string{redacted}
+			}
+			vals[k] = v
+		}
+		return vals
+	default:
+		return raw
+	}
+}
+
+// Format implements fmt.Formatter, redacting the tokens unless RevealSecrets.
+func (t Token) Format(f fmt.State, verb rune) {
+	type token Token // without the Format method
+
+	t.AccessToken = redact(t.AccessToken)
+	t.RefreshToken = redact(t.RefreshToken)
+	t.IDToken = redact(t.IDToken)
+	t.raw = redactParams(t.raw)
+
+	fmt.Fprintf(f, fmt.FormatString(f, verb), token(t))
+}
+
+// LogValue implements slog.LogValuer, redacting the tokens unless
+// RevealSecrets.
+func (t Token) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("access_token", redact(t.AccessToken)),
+		slog.String("token_type", t.Type()),
+		slog.String("refresh_token", redact(t.RefreshToken)),
+		slog.String("id_token", redact(t.IDToken)),
+		slog.Time("expiry", t.Expiry),
+	)
+}
+
+// redactedKey replaces the private keys when formatting.
+type redactedKey struct{}
+
+func (redactedKey) Public() crypto.PublicKey { return nil }
+
+func (redactedKey) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("oauth2: cannot sign with a redacted key")
+}
+
+func (redactedKey) String() string { return redacted }
+
+// Format implements fmt.Formatter, redacting the ClientSecret and the private
+// keys unless RevealSecrets.
+func (c Config) Format(f fmt.State, verb rune) {
+	type config Config // without the Format method
+
+	if !revealSecrets.Load() {
+		c.ClientSecret = redact(c.ClientSecret)
+
+		if c.PrivateKey != nil {
+			c.PrivateKey = redactedKey{}
+		}
+
+		if c.DecryptionKey != nil {
+			c.DecryptionKey = redactedKey{}
+		}
+
+		if c.ClientCertificate != nil {
+			cert := *c.ClientCertificate
+			cert.PrivateKey = redactedKey{}
+			c.ClientCertificate = &cert
+		}
+
+		if c.RequestObject != nil {
+			ro := c.RequestObject.redacted()
+			c.RequestObject = &ro
+		}
+	}
+
+	fmt.Fprintf(f, fmt.FormatString(f, verb), config(c))
+}
+
+// LogValue implements slog.LogValuer, redacting the ClientSecret unless
+// RevealSecrets. The private keys are omitted.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("client_id", c.ClientID),
+		slog.String("client_secret", redact(c.ClientSecret)),
+		slog.String("redirect_url", c.RedirectURL),
+		slog.Any("scopes", c.Scopes),
+		slog.String("issuer", c.Endpoint.Issuer),
+		slog.String("auth_url", c.Endpoint.AuthURL),
+		slog.String("token_url", c.Endpoint.TokenURL),
+	)
+}
+
+// redacted returns a copy of r with the PrivateKey redacted unless
+// RevealSecrets.
+func (r RequestObject) redacted() RequestObject {
+	if r.PrivateKey != nil && !revealSecrets.Load() {
+		r.PrivateKey = redactedKey{}
+	}
+
+	return r
+}
+
+// Format implements fmt.Formatter, redacting the PrivateKey unless
+// RevealSecrets.
+func (r RequestObject) Format(f fmt.State, verb rune) {
+	type requestObject RequestObject // without the Format method
+
+	fmt.Fprintf(f, fmt.FormatString(f, verb), requestObject(r.redacted()))
+}
+
+// Format implements fmt.Formatter, redacting the DeviceCode unless
+// RevealSecrets.
+func (d DeviceAuthResponse) Format(f fmt.State, verb rune) {
+	type deviceAuthResponse DeviceAuthResponse // without the Format method
+
+	d.DeviceCode = redact(d.DeviceCode)
+
+	fmt.Fprintf(f, fmt.FormatString(f, verb), deviceAuthResponse(d))
+}
+
+// LogValue implements slog.LogValuer, redacting the DeviceCode unless
+// RevealSecrets.
+func (d DeviceAuthResponse) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("device_code", redact(d.DeviceCode)),
+		slog.String("user_code", d.UserCode),
+		slog.String("verification_uri", d.VerificationURI),
+		slog.String("verification_uri_complete", d.VerificationURIComplete),
+		slog.Time("expiry", d.Expiry),
+		slog.Int64("interval", d.Interval),
+	)
+}
@@ -0,0 +1,98 @@
+package oauth2
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tok := (&Token{
+		AccessToken:  "SECRET_ACCESS_TOKEN",
+		TokenType:    "Bearer",
+		RefreshToken: "SECRET_REFRESH_TOKEN",
+		IDToken:      "SECRET_ID_TOKEN",
+	}).WithExtra(map[string]any{"access_token": "SECRET_ACCESS_TOKEN", "scope": "openid"})
+
+	conf := &Config{ClientID: "CLIENT_ID", ClientSecret: "SECRET_CLIENT_SECRET"}
+	da := &DeviceAuthResponse{DeviceCode: "SECRET_DEVICE_CODE", UserCode: "USER_CODE"}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	for _, v := range []any{tok, *tok, conf, da} {
+		for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+			fmt.Fprintf(&buf, format+"\n", v)
+		}
+		logger.Info("value", "v", v)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "SECRET") {
+		t.Errorf("Output contains a secret:\n%s", out)
+	}
+	for _, want := range []string{"CLIENT_ID", "USER_CODE", "openid", "client_secret=REDACTED", "v.access_token=REDACTED"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	RevealSecrets(true)
+	defer RevealSecrets(false)
+
+	if got := fmt.Sprintf("%v", tok); !strings.Contains(got, "SECRET_ACCESS_TOKEN") {
+		t.Errorf("Token = %s; want the access token revealed", got)
+	}
+}
+
+func TestRedactConfigKeys(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &Config{
+		ClientID:          "CLIENT_ID",
+		PrivateKey:        key,
+		DecryptionKey:     key,
+		ClientCertificate: &tls.Certificate{PrivateKey: key},
+		RequestObject:     &RequestObject{PrivateKey: key},
+	}
+
+	secrets := []string{
+		strings.Trim(fmt.Sprint([]byte(key)), "[]"),
+		strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%#v", key), "ed25519.PrivateKey{"), "}"),
+	}
+
+	for _, v := range []any{conf, *conf, conf.RequestObject} {
+		for _, format := range []string{"%v", "%+v", "%#v"} {
+			got := fmt.Sprintf(format, v)
+			for _, secret := range secrets {
+				if strings.Contains(got, secret) {
+					t.Errorf("Sprintf(%q) contains the private key: %s", format, got)
+				}
+			}
+		}
+	}
+
+	if conf.PrivateKey == nil || conf.ClientCertificate.PrivateKey == nil {
+		t.Error("Formatting modified the Config")
+	}
+}
+
+func TestBaseErrorOmitsBody(t *testing.T) {
+	e := &BaseError{
+		Response: &http.Response{Status: "400 Bad Request", StatusCode: http.StatusBadRequest},
+		Body:     []byte(`{"access_token":"SECRET_ACCESS_TOKEN"}`),
+	}
+
+	if got, want := e.Error(), "oauth2: request failed: 400 Bad Request"; got != want {
+		t.Errorf("Error() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,215 @@
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+// DefaultRequestObjectExpires is the lifetime of a request object when
+// RequestObject.Expires is zero.
+const DefaultRequestObjectExpires = 5 * time.Minute
+
+// RequestObject describes how the authorization request parameters are packed
+// into a signed, and optionally encrypted, request object as described in
+// RFC 9101 JWT-Secured Authorization Request (JAR).
+//
+// When a Config has a RequestObject, AuthCodeURL, ParsedAuthCodeURL and
+// PushedAuth only send the 'client_id' and 'request' parameters, with every
+// other parameter contained in the request object. Use ParsedAuthCodeURL
+// rather than AuthCodeURL to obtain the error of creating the request object.
+type RequestObject struct {
+	// PrivateKey signs the request object. Its public key must be registered
+	// for the client, such as in the 'jwks' or 'jwks_uri' client metadata.
+	PrivateKey crypto.Signer
+
+	// PrivateKeyID optionally specifies the 'kid' header of the request object.
+	PrivateKeyID string
+
+	// Algorithm optionally specifies the JWS algorithm used to sign the
+	// request object. If empty, it's derived from the PrivateKey.
+	Algorithm string
+
+	// Expires optionally specifies how long the request object is valid for.
+	// If zero, DefaultRequestObjectExpires is used.
+	Expires time.Duration
+
+	// Encrypt nests the signed request object in a JWE encrypted to the
+	// authorization server's EncryptionKey. If EncryptionKey is nil, the
+	// encryption key with the EncryptionKeyID, or the first one if it's empty,
+	// is resolved from the Config.KeySet.
+	Encrypt bool
+
+	// EncryptionKey is the authorization server's optional public key. When
+	// set, the request object is encrypted to it even if Encrypt is false.
+	// *rsa.PublicKey, *ecdsa.PublicKey and *ecdh.PublicKey keys are supported.
+	EncryptionKey crypto.PublicKey
+
+	// EncryptionKeyID optionally specifies the 'kid' header of the JWE.
+	EncryptionKeyID string
+
+	// EncryptionAlgorithm optionally specifies the JWE key management
+	// algorithm. If empty, RSA-OAEP-256 is used for RSA keys and
+	// ECDH-ES+A256KW for elliptic curve keys.
+	EncryptionAlgorithm string
+
+	// EncryptionMethod optionally specifies the JWE content encryption
+	// algorithm. If empty, A128CBC-HS256 is used.
+	EncryptionMethod string
+}
+
+// requestObjectValues returns the front channel parameters of an authorization
+// request with the parameters v. When the Config has a RequestObject, v is
+// packed into it, otherwise v is returned unchanged. The provided context is
+// used to resolve the encryption key from the Config.KeySet.
+func (c *Config) requestObjectValues(ctx context.Context, v url.Values) (url.Values, error) {
+	if c.RequestObject == nil {
+		return v, nil
+	}
+
+	request, err := c.RequestObject.encode(ctx, c, v)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot create request object: %w", err)
+	}
+
+	return url.Values{
+		"client_id": {c.ClientID},
+		"request":   {request},
+	}, nil
+}
+
+func (r *RequestObject) encode(ctx context.Context, c *Config, v url.Values) (string, error) {
+	if r.PrivateKey == nil {
+		return "", errors.New("no private key was provided")
+	}
+
+	signer, alg, err := jws.NewSigner(r.Algorithm, r.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := getRandomBytes(32, charsetRFC3986Unreserved)
+	if err != nil {
+		return "", err
+	}
+
+	claims := map[string]any{}
+
+	for key, values := range v {
+		claims[key] = requestObjectClaim(key, values)
+	}
+
+	audience := c.Endpoint.Issuer
+	if audience == "" {
+		audience = c.Endpoint.AuthURL
+	}
+
+	expires := r.Expires
+	if expires == 0 {
+		expires = DefaultRequestObjectExpires
+	}
+
+	now := timeNow()
+
+	claims["iss"] = c.ClientID
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(expires).Unix()
+	claims["jti"] = string(jti)
+
+	header := map[string]any{
+		"alg": alg,
+		"typ": "oauth-authz-req+jwt",
+	}
+
+	if r.PrivateKeyID != "" {
+		header["kid"] = r.PrivateKeyID
+	}
+
+	request, err := jws.EncodeClaimsWithSigner(header, claims, signer)
+	if err != nil || (r.EncryptionKey == nil && !r.Encrypt) {
+		return request, err
+	}
+
+	return r.encrypt(ctx, c, request)
+}
+
+func (r *RequestObject) encrypt(ctx context.Context, c *Config, request string) (string, error) {
+	header := &jwe.Header{
+		Algorithm:   r.EncryptionAlgorithm,
+		Encryption:  r.EncryptionMethod,
+		KeyID:       r.EncryptionKeyID,
+		ContentType: "oauth-authz-req+jwt",
+	}
+
+	key := r.EncryptionKey
+
+	if key == nil {
+		ks, err := c.KeySet()
+		if err != nil {
+			return "", err
+		}
+
+		jwk, err := ks.EncryptionKey(ctx, r.EncryptionKeyID)
+		if err != nil {
+			return "", err
+		}
+
+		key, header.KeyID = jwk.Key, jwk.KeyID
+
+		if header.Algorithm == "" {
+			header.Algorithm = jwk.Algorithm
+		}
+	}
+
+	if header.Algorithm == "" {
+		switch key.(type) {
+		case *rsa.PublicKey:
+			header.Algorithm = "RSA-OAEP-256"
+		case *ecdsa.PublicKey, *ecdh.PublicKey:
+			header.Algorithm = "ECDH-ES+A256KW"
+		default:
+			return "", fmt.Errorf("unsupported encryption key type %T", key)
+		}
+	}
+
+	if header.Encryption == "" {
+		header.Encryption = "A128CBC-HS256"
+	}
+
+	return jwe.Encrypt([]byte(request), header, key)
+}
+
+// requestObjectClaim returns the claim representing the authorization request
+// parameter key. Parameters with multiple values become arrays, and the
+// 'max_age' and 'claims' parameters retain their JSON types.
+func requestObjectClaim(key string, values []string) any {
+	if len(values) != 1 {
+		return values
+	}
+
+	switch key {
+	case "max_age":
+		if n, err := strconv.ParseInt(values[0], 10, 64); err == nil {
+			return n
+		}
+	case "claims", "authorization_details":
+		if json.Valid([]byte(values[0])) {
+			return json.RawMessage(values[0])
+		}
+	}
+
+	return values[0]
+}
@@ -0,0 +1,195 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestAuthCodeURL_RequestObject(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := newConf("https://as.example.com")
+	conf.Endpoint.Issuer = "https://as.example.com"
+	conf.RequestObject = &RequestObject{PrivateKey: key, PrivateKeyID: "key1"}
+
+	authURL, err := conf.ParsedAuthCodeURL("state", SetAuthURLParam("max_age", "300"), SetAuthURLParam("claims", `{"id_token":{"acr":null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := authURL.Query()
+	if len(params) != 2 || params.Get("client_id") != "CLIENT_ID" {
+		t.Fatalf("Unexpected front channel parameters %v", params)
+	}
+
+	claims := verifyRequestObject(t, params.Get("request"), key)
+	for k, want := range map[string]any{
+		"iss":           "CLIENT_ID",
+		"aud":           "https://as.example.com",
+		"client_id":     "CLIENT_ID",
+		"response_type": "code",
+		"redirect_uri":  "REDIRECT_URL",
+		"scope":         "scope1 scope2",
+		"state":         "state",
+		"max_age":       float64(300),
+	} {
+		if claims[k] != want {
+			t.Errorf("claim %s = %v; want %v", k, claims[k], want)
+		}
+	}
+	if _, ok := claims["claims"].(map[string]any); !ok {
+		t.Errorf("claim claims = %v; want a JSON object", claims["claims"])
+	}
+	for _, k := range []string{"exp", "nbf", "iat", "jti"} {
+		if claims[k] == nil {
+			t.Errorf("claim %s is missing", k)
+		}
+	}
+
+	if got := conf.AuthCodeURL("state"); got == "" || got[:len("https://as.example.com/auth?")] != "https://as.example.com/auth?" {
+		t.Errorf("AuthCodeURL = %q; want a URL", got)
+	}
+
+	conf.RequestObject = &RequestObject{}
+	if _, err = conf.ParsedAuthCodeURL("state"); err == nil {
+		t.Error("ParsedAuthCodeURL without a private key = nil error; want error")
+	}
+	if got := conf.AuthCodeURL("state"); got != "" {
+		t.Errorf("AuthCodeURL without a private key = %q; want empty", got)
+	}
+}
+
+func TestPushAuthRequest_EncryptedRequestObject(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var authURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		v, _ := url.ParseQuery(string(body))
+		if len(v) != 2 || v.Get("client_id") != "CLIENT_ID" {
+			t.Errorf("Unexpected par payload %q", body)
+		}
+		if r.Header.Get("Authorization") == "" {
+			t.Error("Missing client authentication")
+		}
+		plaintext, header, err := jwe.Decrypt(v.Get("request"), encryptionKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Algorithm != "RSA-OAEP-256" || header.Encryption != "A128CBC-HS256" || header.KeyID != "enc1" {
+			t.Errorf("Unexpected JWE header %+v", header)
+		}
+		claims := verifyRequestObject(t, string(plaintext), signingKey)
+		if claims["state"] != "state" || claims["aud"] != authURL {
+			t.Errorf("Unexpected claims %v", claims)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"request_uri": "urn:ietf:params:oauth:request_uri:abc", "expires_in": 60}`)
+	}))
+	defer ts.Close()
+
+	c := newConf(ts.URL)
+	authURL = c.Endpoint.AuthURL
+	c.Endpoint.AuthStyle = AuthStyleInHeader
+	c.RequestObject = &RequestObject{
+		PrivateKey:      signingKey,
+		EncryptionKey:   &encryptionKey.PublicKey,
+		EncryptionKeyID: "enc1",
+	}
+	u, _, err := c.PushedAuth(context.Background(), "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("request_uri"); got != "urn:ietf:params:oauth:request_uri:abc" {
+		t.Errorf("Unexpected request_uri %q", got)
+	}
+}
+
+func TestPushAuthRequest_RequestObjectKeySetEncryption(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/jwks" {
+			sig, _ := jws.PublicJWK(signingKey.Public())
+			sig["use"] = "sig"
+			enc, _ := jws.PublicJWK(&encryptionKey.PublicKey)
+			enc["use"], enc["kid"] = "enc", "enc2"
+			json.NewEncoder(w).Encode(map[string]any{"keys": []any{sig, enc}})
+			return
+		}
+		r.ParseForm()
+		plaintext, header, err := jwe.Decrypt(r.PostForm.Get("request"), encryptionKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.KeyID != "enc2" || header.Algorithm != "RSA-OAEP-256" {
+			t.Errorf("Unexpected JWE header %+v", header)
+		}
+		verifyRequestObject(t, string(plaintext), signingKey)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"request_uri": "urn:ietf:params:oauth:request_uri:abc", "expires_in": 60}`)
+	}))
+	defer ts.Close()
+
+	c := newConf(ts.URL)
+	c.Endpoint.AuthStyle = AuthStyleInHeader
+	c.Endpoint.JWKSURL = ts.URL + "/jwks"
+	c.RequestObject = &RequestObject{PrivateKey: signingKey, Encrypt: true}
+	if _, _, err = c.PushedAuth(context.Background(), "state"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Endpoint.JWKSURL = ""
+	if _, _, err = c.PushedAuth(context.Background(), "state"); err == nil {
+		t.Error("PushedAuth without an encryption key = nil error; want error")
+	}
+}
+
+func verifyRequestObject(t *testing.T, request string, key *ecdsa.PrivateKey) map[string]any {
+	t.Helper()
+	header, err := jws.DecodeHeader(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Typ != "oauth-authz-req+jwt" {
+		t.Errorf("Unexpected request object typ %q", header.Typ)
+	}
+	payload, err := jws.VerifyWithKey(request, jws.ES256, key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]any
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
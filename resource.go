@@ -0,0 +1,208 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ResourceOption builds an AuthCodeOption which passes the RFC 8707 'resource'
+// parameters, the absolute URIs of the resource servers the token is intended
+// for. When requested in an authorization request, a ResourceTokenSource can
+// then obtain an audience restricted access token for each of them.
+//
+// See https://datatracker.ietf.org/doc/html/rfc8707#section-2.
+func ResourceOption(resources ...string) AuthCodeOption {
+	return resourceOption(resources)
+}
+
+type resourceOption []string
+
+func (o resourceOption) setValue(m url.Values) {
+	m["resource"] = append([]string(nil), o...)
+}
+
+// ResourceTokenSource holds a separate access token for each RFC 8707 resource
+// indicator, which are all obtained with the refresh grant from a single
+// refresh token. Refreshes are serialized so a rotated refresh token is used
+// for the following ones.
+//
+// A ResourceTokenSource is safe for concurrent use.
+type ResourceTokenSource struct {
+	ctx  context.Context
+	conf *Config
+
+	mu           sync.Mutex // guards refreshToken and sources
+	refreshToken string
+	sources      map[string]*reuseTokenSource
+}
+
+// ResourceTokenSource returns a ResourceTokenSource which obtains the access
+// token of each resource by refreshing t using the provided context. The
+// access token of t is returned for the empty resource, and is refreshed
+// without a 'resource' parameter.
+func (c *Config) ResourceTokenSource(ctx context.Context, t *Token) *ResourceTokenSource {
+	s := &ResourceTokenSource{
+		ctx:     ctx,
+		conf:    c,
+		sources: map[string]*reuseTokenSource{},
+	}
+
+	if t != nil {
+		s.refreshToken = t.RefreshToken
+	}
+
+	s.sources[""] = &reuseTokenSource{t: t, new: &resourceRefresher{s, ""}, ctx: c.hooksContext(ctx)}
+
+	return s
+}
+
+// TokenSource returns the TokenSource of the access token for resource. It
+// returns the same cached token until it expires.
+func (s *ResourceTokenSource) TokenSource(resource string) TokenSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.sources[resource]
+	if !ok {
+		src = &reuseTokenSource{new: &resourceRefresher{s, resource}, ctx: s.conf.hooksContext(s.ctx)}
+		s.sources[resource] = src
+	}
+
+	return src
+}
+
+// Token returns the access token for resource, refreshing it if necessary.
+func (s *ResourceTokenSource) Token(resource string) (*Token, error) {
+	return s.TokenSource(resource).Token()
+}
+
+// resourceRefresher is a TokenSource that makes "grant_type"=="refresh_token"
+// HTTP requests for the resource with the refresh token of a
+// ResourceTokenSource.
+type resourceRefresher struct {
+	s        *ResourceTokenSource
+	resource string
+}
+
+func (r *resourceRefresher) Token() (*Token, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.refreshToken == "" {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+
+	v := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {r.s.refreshToken},
+	}
+
+	if r.resource != "" {
+		v.Set("resource", r.resource)
+	}
+
+	tk, err := retrieveToken(r.s.ctx, r.s.conf, v)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.refreshToken = tk.RefreshToken
+
+	return tk, nil
+}
+
+// ResourceTransport is an http.RoundTripper that makes OAuth 2.0 HTTP requests
+// with the access token of the resource matching the request URL, wrapping a
+// base RoundTripper as Transport does.
+type ResourceTransport struct {
+	// Source supplies the token of each resource.
+	Source *ResourceTokenSource
+
+	// Resources maps the URL prefixes of requests, such as
+	// "https://api.example.com/v1", to their resource indicator. The longest
+	// matching prefix is used, and requests matching none of them fail rather
+	// than disclosing a token to an unexpected server. A prefix matches URLs
+	// with the same scheme and host, and with a path within its path.
+	Resources map[string]string
+
+	// Base is the base RoundTripper used to make HTTP requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// DPoP, RefreshOnInvalidToken and ChallengeErrors are the same as the
+	// Transport fields.
+	DPoP                  *DPoP
+	RefreshOnInvalidToken bool
+	ChallengeErrors       bool
+}
+
+// RoundTrip authorizes and authenticates the request with the access token of
+// the resource matching the request URL.
+func (t *ResourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Source == nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errors.New("oauth2: ResourceTransport's Source is nil")
+	}
+
+	resource, err := t.resource(req.URL)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	tr := &Transport{
+		Source:                t.Source.TokenSource(resource),
+		Base:                  t.Base,
+		DPoP:                  t.DPoP,
+		RefreshOnInvalidToken: t.RefreshOnInvalidToken,
+		ChallengeErrors:       t.ChallengeErrors,
+	}
+
+	return tr.RoundTrip(req)
+}
+
+// resource returns the resource of the longest prefix matching u.
+func (t *ResourceTransport) resource(u *url.URL) (string, error) {
+	var (
+		resource string
+		longest  = -1
+	)
+
+	for raw, r := range t.Resources {
+		prefix, err := url.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("oauth2: cannot parse resource prefix %q: %w", raw, err)
+		}
+
+		if n := len(prefix.Path); n > longest && urlHasPrefix(u, prefix) {
+			resource, longest = r, n
+		}
+	}
+
+	if longest < 0 {
+		return "", fmt.Errorf("oauth2: no resource matches the request URL %q", u.Redacted())
+	}
+
+	return resource, nil
+}
+
+// urlHasPrefix reports whether u has the scheme and host of prefix, and a path
+// equal to or within the path of prefix.
+func urlHasPrefix(u, prefix *url.URL) bool {
+	if !strings.EqualFold(u.Scheme, prefix.Scheme) || !strings.EqualFold(u.Host, prefix.Host) {
+		return false
+	}
+
+	path := strings.TrimSuffix(prefix.Path, "/")
+
+	return u.Path == path || strings.HasPrefix(u.Path, path+"/")
+}
@@ -0,0 +1,136 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResourceOption(t *testing.T) {
+	conf := newConf("https://example.com/token")
+
+	u, err := url.Parse(conf.AuthCodeURL("state", ResourceOption("https://api.example.com", "https://other.example.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(u.Query()["resource"], " "), "https://api.example.com https://other.example.com"; got != want {
+		t.Errorf("resource = %q; want %q", got, want)
+	}
+}
+
+func TestResourceTokenSource(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []url.Values
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		n := len(requests)
+		requests = append(requests, r.PostForm)
+		mu.Unlock()
+
+		if got, want := r.PostForm.Get("refresh_token"), fmt.Sprintf("REFRESH_TOKEN_%d", n); got != want {
+			t.Errorf("refresh_token = %q; want %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"ACCESS_TOKEN_%s","token_type":"bearer","refresh_token":"REFRESH_TOKEN_%d","expires_in":3600}`, r.PostForm.Get("resource"), n+1)
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+
+	src := conf.ResourceTokenSource(context.Background(), &Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN_0", Expiry: time.Now().Add(time.Hour)})
+
+	for _, resource := range []string{"", "https://a.example.com", "https://b.example.com", "https://a.example.com"} {
+		tk, err := src.Token(resource)
+		if err != nil {
+			t.Fatalf("Token(%q) = %v", resource, err)
+		}
+
+		want := "ACCESS_TOKEN_" + resource
+		if resource == "" {
+			want = "ACCESS_TOKEN"
+		}
+		if tk.AccessToken != want {
+			t.Errorf("Token(%q) = %q; want %q", resource, tk.AccessToken, want)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Token requests = %d; want 2", len(requests))
+	}
+
+	for i, want := range []string{"https://a.example.com", "https://b.example.com"} {
+		if got := requests[i]["resource"]; len(got) != 1 || got[0] != want {
+			t.Errorf("Request %d resource = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestResourceTransport(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	src := (&Config{}).ResourceTokenSource(context.Background(), nil)
+	for _, resource := range []string{"https://v1.example.com", "https://admin.example.com"} {
+		src.sources[resource] = &reuseTokenSource{t: &Token{AccessToken: resource}}
+	}
+
+	client := &http.Client{Transport: &ResourceTransport{
+		Source: src,
+		Resources: map[string]string{
+			api.URL + "/v1":       "https://v1.example.com",
+			api.URL + "/v1/admin": "https://admin.example.com",
+		},
+	}}
+
+	tests := []struct {
+		path string
+		want string
+		err  bool
+	}{
+		{"/v1", "Bearer https://v1.example.com", false},
+		{"/v1/users", "Bearer https://v1.example.com", false},
+		{"/v1/admin/users", "Bearer https://admin.example.com", false},
+		{"/v1admin", "", true},
+		{"/v2", "", true},
+	}
+
+	for _, tt := range tests {
+		resp, err := client.Get(api.URL + tt.path)
+		if tt.err {
+			if err == nil {
+				resp.Body.Close()
+				t.Errorf("Get(%q) succeeded; want an error", tt.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Get(%q) = %v", tt.path, err)
+			continue
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want {
+			t.Errorf("Get(%q) Authorization = %q; want %q", tt.path, body, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package oauth2
+
+import (
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+)
+
+// The RetryPolicy defaults.
+const (
+	DefaultRetryMaxAttempts = internal.DefaultRetryMaxAttempts
+	DefaultRetryMinBackoff  = internal.DefaultRetryMinBackoff
+	DefaultRetryMaxBackoff  = internal.DefaultRetryMaxBackoff
+)
+
+// RetryPolicy configures how requests to the authorization server's token,
+// PAR, device authorization, backchannel authentication, introspection and
+// revocation endpoints are retried when they fail with a transient error.
+//
+// Network errors, the 429, 502, 503 and 504 status codes, and RFC 6749's
+// 'temporarily_unavailable' error code are transient, see BaseError.Temporary.
+// Retries are made after the delay of the server's Retry-After header, or
+// otherwise with capped exponential backoff and jitter. They're never made
+// when the Retry-After delay exceeds the MaxBackoff, or when they would start
+// after the request context's deadline.
+type RetryPolicy struct {
+	// MaxAttempts optionally specifies the maximum number of attempts of a
+	// request, including the first. If zero, DefaultRetryMaxAttempts is used.
+	MaxAttempts int
+
+	// MinBackoff optionally specifies the delay before the first retry. If
+	// zero, DefaultRetryMinBackoff is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff optionally specifies the maximum delay between retries. If
+	// zero, DefaultRetryMaxBackoff is used.
+	MaxBackoff time.Duration
+
+	// RetryNonIdempotent allows requests which aren't idempotent to be
+	// retried. A failed request may have been processed by the server, so
+	// retrying an authorization code exchange is likely to fail as the code
+	// has been used, retrying a refresh is likely to fail as the refresh
+	// token has been rotated, and retrying a backchannel authentication
+	// request may prompt the end-user again.
+	RetryNonIdempotent bool
+}
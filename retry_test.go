@@ -0,0 +1,184 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryServer(t *testing.T, failures int32, fail func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			fail(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"ACCESS_TOKEN","token_type":"bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int32
+	}{
+		{"service unavailable", http.StatusServiceUnavailable, "", 3},
+		{"bad gateway", http.StatusBadGateway, "", 3},
+		{"temporarily unavailable", http.StatusBadRequest, `{"error":"temporarily_unavailable"}`, 3},
+		{"invalid grant", http.StatusBadRequest, `{"error":"invalid_grant"}`, 1},
+		{"internal server error", http.StatusInternalServerError, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, requests := newRetryServer(t, 2, func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			conf := newConf(ts.URL)
+			conf.Endpoint.AuthStyle = AuthStyleInParams
+			conf.Retry = &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+			_, err := conf.Token(context.Background(), SetAuthURLParam("grant_type", "client_credentials"))
+			if got := requests.Load(); got != tt.want {
+				t.Errorf("Token made %d requests; want %d (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyCodeExchange(t *testing.T) {
+	ts, requests := newRetryServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+
+	_, err := conf.Exchange(context.Background(), "CODE")
+	var rErr *RetrieveError
+	if !errors.As(err, &rErr) || !rErr.Temporary() || requests.Load() != 1 {
+		t.Errorf("Exchange = %v after %d requests; want temporary RetrieveError after 1 request", err, requests.Load())
+	}
+
+	conf.Retry.RetryNonIdempotent = true
+	requests.Store(0)
+	if _, err = conf.Exchange(context.Background(), "CODE"); err != nil || requests.Load() != 2 {
+		t.Errorf("Exchange = %v after %d requests; want success after 2 requests", err, requests.Load())
+	}
+}
+
+func TestRetryPolicyRefresh(t *testing.T) {
+	ts, requests := newRetryServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+
+	// The refresh token may have been rotated by the failed request.
+	_, err := conf.TokenSource(context.Background(), &Token{RefreshToken: "REFRESH_TOKEN"}).Token()
+	if err == nil || requests.Load() != 1 {
+		t.Errorf("Token = %v after %d requests; want error after 1 request", err, requests.Load())
+	}
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	ts, requests := newRetryServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Retry = &RetryPolicy{MinBackoff: time.Millisecond}
+
+	start := time.Now()
+	if _, err := conf.Token(context.Background(), SetAuthURLParam("grant_type", "client_credentials")); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || requests.Load() != 2 {
+		t.Errorf("Token retried after %v with %d requests; want after Retry-After of 1s with 2 requests", elapsed, requests.Load())
+	}
+
+	// A retry which would start after the deadline isn't made.
+	requests.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := conf.Token(ctx, SetAuthURLParam("grant_type", "client_credentials")); err == nil || requests.Load() != 1 {
+		t.Errorf("Token with deadline = %v after %d requests; want error after 1 request", err, requests.Load())
+	}
+
+	// Nor one after a delay exceeding the MaxBackoff.
+	requests.Store(0)
+	conf.Retry.MaxBackoff = 500 * time.Millisecond
+	if _, err := conf.Token(context.Background(), SetAuthURLParam("grant_type", "client_credentials")); err == nil || requests.Load() != 1 {
+		t.Errorf("Token with MaxBackoff = %v after %d requests; want error after 1 request", err, requests.Load())
+	}
+}
+
+func TestRetryPolicyNetworkError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	var attempts atomic.Int32
+	ctx := context.WithValue(context.Background(), HTTPClient, &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return http.DefaultTransport.RoundTrip(r)
+	})})
+
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	conf.Retry = &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}
+
+	if _, err := conf.Token(ctx, SetAuthURLParam("grant_type", "client_credentials")); err == nil || attempts.Load() != 2 {
+		t.Errorf("Token = %v after %d attempts; want error after 2 attempts", err, attempts.Load())
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRetryPolicyDeviceAuth(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"temporarily_unavailable","error_description":"Try again"}`))
+			return
+		}
+		w.Write([]byte(`{"device_code":"DEVICE_CODE","user_code":"USER_CODE","verification_uri":"https://example.com/device","expires_in":300}`))
+	}))
+	defer ts.Close()
+
+	conf := &Config{
+		ClientID: "CLIENT_ID",
+		Endpoint: Endpoint{DeviceAuthURL: ts.URL},
+		Retry:    &RetryPolicy{MinBackoff: time.Millisecond},
+	}
+
+	if _, err := conf.DeviceAuth(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("DeviceAuth = %v after %d requests; want success after 2 requests", err, requests.Load())
+	}
+
+	// The deadline of the context applies to each attempt.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conf.DeviceAuth(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeviceAuth with canceled context = %v; want %v", err, context.Canceled)
+	}
+}
@@ -14,7 +14,7 @@ func (c *Config) RevokeToken(ctx context.Context, token *Token, opts ...Revocati
 		return fmt.Errorf("error revoking token: no token was provided")
 	}
 
-	if c.Endpoint.RevocationURL == "" {
+	if c.revocationURL() == "" {
 		return fmt.Errorf("error revoking token: no revocation endpoint URL was provided")
 	}
 
@@ -60,7 +60,7 @@ func (c *Config) RevokeToken(ctx context.Context, token *Token, opts ...Revocati
 	}
 
 	for _, v := range vals {
-		if err = internal.RevokeToken(ctx, c.ClientID, c.ClientSecret, c.Endpoint.RevocationURL, v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get()); err != nil {
+		if err = internal.RevokeToken(ctx, c.clientAuth(), c.revocationURL(), v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get()); err != nil {
 			if rErr, ok := err.(*internal.RevokeError); ok {
 				xErr := (*BaseError)(rErr)
 
@@ -1,6 +1,9 @@
 package oauth2
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +44,26 @@ func TestAddRevocationTokenTypeHints(t *testing.T) {
 		})
 	}
 }
+
+func TestRevokeToken_ClientSecretJWT(t *testing.T) {
+	var tokenURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/revoke" {
+			t.Errorf("Unexpected revocation URL %q", r.URL)
+		}
+		checkClientSecretJWT(t, r, tokenURL)
+		if got := r.FormValue("token"); got != "ACCESS_TOKEN" {
+			t.Errorf("Unexpected token %q", got)
+		}
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.ClientSecret = clientSecretJWTSecret
+	conf.Endpoint.AuthStyle = AuthStyleClientSecretJWT
+	conf.Endpoint.RevocationURL = ts.URL + "/revoke"
+	conf.ClientAssertionAlgorithm = "HS384"
+	tokenURL = conf.Endpoint.TokenURL
+	if err := conf.RevokeToken(context.Background(), &Token{AccessToken: "ACCESS_TOKEN"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,139 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultStaleMinBackoff is the delay before the first retry of a failed
+	// refresh when StaleOptions.MinBackoff is zero.
+	DefaultStaleMinBackoff = time.Second
+
+	// DefaultStaleMaxBackoff is the maximum delay between retries of a failed
+	// refresh when StaleOptions.MaxBackoff is zero.
+	DefaultStaleMaxBackoff = time.Minute
+)
+
+// StaleOptions configures how a TokenSource returned by
+// ReuseTokenSourceWithStale serves its cached token when it can't be
+// refreshed.
+type StaleOptions struct {
+	// GracePeriod optionally specifies how long after its Expiry the token
+	// may still be served. If zero, it's served until its Expiry, which is
+	// later than when it's considered expired by Token.Valid.
+	GracePeriod time.Duration
+
+	// MinBackoff optionally specifies the delay before the first retry of a
+	// failed refresh. If zero, DefaultStaleMinBackoff is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff optionally specifies the maximum delay between retries of a
+	// failed refresh. If zero, DefaultStaleMaxBackoff is used.
+	MaxBackoff time.Duration
+}
+
+// ReuseTokenSourceWithStale returns a TokenSource that acts in the same manner
+// as the TokenSource returned by ReuseTokenSource, except that when the token
+// can't be refreshed because of a transient error, such as a network failure
+// or a response for which RetrieveError.Temporary reports true, the cached
+// token continues to be returned until its Expiry plus the opts.GracePeriod.
+//
+// While the cached token is served, the refresh is retried with exponential
+// backoff rather than on every call. The tokens served in this way report
+// true from Token.Stale.
+func ReuseTokenSourceWithStale(t *Token, src TokenSource, opts StaleOptions) TokenSource {
+	// Don't wrap a reuseTokenSource in itself. That would work,
+	// but cause an unnecessary number of mutex operations.
+	// Just build the equivalent one, which starts from its cached token
+	// without changing how it's served by src.
+	var (
+		ctx         context.Context
+		expiryDelta time.Duration
+	)
+	if rt, ok := src.(*reuseTokenSource); ok {
+		if t == nil {
+			rt.mu.Lock()
+			t = rt.t
+			rt.mu.Unlock()
+		}
+		src, ctx, expiryDelta = rt.new, rt.ctx, rt.expiryDelta
+	}
+	return &reuseTokenSource{
+		t:           t,
+		new:         src,
+		ctx:         ctx,
+		expiryDelta: expiryDelta,
+		stale:       &opts,
+	}
+}
+
+// usable reports whether t may be served stale.
+func (o *StaleOptions) usable(t *Token) bool {
+	if t == nil || t.AccessToken == "" || t.Expiry.IsZero() {
+		return false
+	}
+
+	return timeNow().Before(t.Expiry.Round(0).Add(o.GracePeriod))
+}
+
+// nextBackoff returns the delay before the retry following one made after
+// backoff.
+func (o *StaleOptions) nextBackoff(backoff time.Duration) time.Duration {
+	minBackoff, maxBackoff := o.MinBackoff, o.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultStaleMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultStaleMaxBackoff
+	}
+
+	if backoff < minBackoff {
+		return minBackoff
+	}
+
+	if backoff *= 2; backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
+
+// Stale reports whether t was served after it expired because it couldn't
+// be refreshed. See ReuseTokenSourceWithStale.
+func (t *Token) Stale() bool {
+	return t != nil && t.stale
+}
+
+// asStale returns a copy of t which reports it's stale.
+func (t *Token) asStale() *Token {
+	t2 := *t
+	t2.stale = true
+	return &t2
+}
+
+// isTransient reports whether err is a network failure or timeout reaching
+// the token endpoint, or a response indicating it's temporarily unable to
+// issue tokens as reported by RetrieveError.Temporary.
+func isTransient(err error) bool {
+	var rErr *RetrieveError
+	if errors.As(err, &rErr) {
+		return rErr.Temporary()
+	}
+
+	var uErr *url.Error
+	if !errors.As(err, &uErr) || errors.Is(uErr.Err, context.Canceled) {
+		return false
+	}
+
+	// The url.Error itself is a net.Error, so it's the error it wraps which
+	// is classified.
+	var nErr net.Error
+
+	return errors.As(uErr.Err, &nErr) || errors.Is(uErr.Err, context.DeadlineExceeded) ||
+		errors.Is(uErr.Err, io.EOF) || errors.Is(uErr.Err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,134 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReuseTokenSourceWithStale(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+
+	var requests atomic.Int32
+	var available atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"NEW_ACCESS_TOKEN","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	// The token is past its early expiry window but not its Expiry.
+	tok := &Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: now.Add(5 * time.Second)}
+	conf := newConf(ts.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	base := conf.TokenSource(context.Background(), tok)
+	src := ReuseTokenSourceWithStale(nil, base, StaleOptions{GracePeriod: time.Minute})
+	if base.(*reuseTokenSource).stale != nil {
+		t.Error("ReuseTokenSourceWithStale changed the reuseTokenSource it was passed")
+	}
+
+	got, err := src.Token()
+	if err != nil || got.AccessToken != "ACCESS_TOKEN" || !got.Stale() {
+		t.Fatalf("Token = %v, %v; want stale ACCESS_TOKEN", got, err)
+	}
+	if tok.Stale() {
+		t.Error("Cached token was marked stale")
+	}
+
+	// The refresh isn't retried until the backoff elapses.
+	if got, err = src.Token(); err != nil || !got.Stale() || requests.Load() != 1 {
+		t.Errorf("Token = %v, %v after %d requests; want stale token after 1 request", got, err, requests.Load())
+	}
+
+	now = now.Add(DefaultStaleMinBackoff)
+	if _, err = src.Token(); err != nil || requests.Load() != 2 {
+		t.Errorf("Token = %v after %d requests; want stale token after 2 requests", err, requests.Load())
+	}
+
+	// Past the grace period the error is returned.
+	now = tok.Expiry.Add(time.Minute)
+	var rErr *RetrieveError
+	if _, err = src.Token(); !errors.As(err, &rErr) {
+		t.Errorf("Token past grace period error = %v; want RetrieveError", err)
+	}
+
+	available.Store(true)
+	if got, err = src.Token(); err != nil || got.AccessToken != "NEW_ACCESS_TOKEN" || got.Stale() {
+		t.Errorf("Token = %v, %v; want fresh NEW_ACCESS_TOKEN", got, err)
+	}
+}
+
+func TestReuseTokenSourceWithStaleNonTransient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer ts.Close()
+
+	tok := &Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(5 * time.Second)}
+	src := ReuseTokenSourceWithStale(tok, newConf(ts.URL).TokenSource(context.Background(), tok), StaleOptions{})
+
+	var rErr *RetrieveError
+	if _, err := src.Token(); !errors.As(err, &rErr) || rErr.ErrorCode != "invalid_grant" {
+		t.Errorf("Token error = %v; want RetrieveError invalid_grant", err)
+	}
+}
+
+func TestStaleOptionsBackoff(t *testing.T) {
+	o := &StaleOptions{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	var backoff time.Duration
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if backoff = o.nextBackoff(backoff); backoff != want {
+			t.Errorf("nextBackoff = %v; want %v", backoff, want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	retrieveError := func(status int, code string) error {
+		return &RetrieveError{BaseError: &BaseError{Response: &http.Response{StatusCode: status}, ErrorCode: code}}
+	}
+	urlError := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://example.com/token", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"service unavailable", retrieveError(http.StatusServiceUnavailable, ""), true},
+		{"temporarily unavailable", retrieveError(http.StatusBadRequest, "temporarily_unavailable"), true},
+		{"internal server error", retrieveError(http.StatusInternalServerError, ""), false},
+		{"invalid grant", retrieveError(http.StatusBadRequest, "invalid_grant"), false},
+		{"connection refused", urlError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), true},
+		{"timeout", urlError(context.DeadlineExceeded), true},
+		{"connection closed", urlError(io.ErrUnexpectedEOF), true},
+		{"canceled", urlError(context.Canceled), false},
+		{"unsupported scheme", urlError(errors.New("unsupported protocol scheme")), false},
+		{"other", errors.New("oauth2: cannot parse token"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: isTransient = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
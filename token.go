@@ -45,6 +45,10 @@ type Token struct {
 	// IDToken is the OpenID Connect 1.0 ID Token.
 	IDToken string `json:"id_token,omitempty"`
 
+	// IssuedTokenType is the RFC 8693 type of the token issued by a token
+	// exchange, such as TokenTypeAccessToken.
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+
 	// Expiry is the optional expiration time of the access token.
 	//
 	// If zero, TokenSource implementations will reuse the same
@@ -67,6 +71,10 @@ type Token struct {
 	// expired, by subtracting from Expiry. If zero, defaultExpiryDelta
 	// is used.
 	expiryDelta time.Duration
+
+	// stale is set when the token is returned after it expired because it
+	// couldn't be refreshed.
+	stale bool
 }
 
 // Type returns t.TokenType if non-empty, else "Bearer".
@@ -83,6 +91,10 @@ func (t *Token) Type() string {
 		return "Basic"
 	}
 
+	if strings.EqualFold(t.TokenType, "dpop") {
+		return "DPoP"
+	}
+
 	if t.TokenType != "" {
 		return t.TokenType
 	}
@@ -166,12 +178,13 @@ func tokenFromInternal(t *internal.Token) *Token {
 		return nil
 	}
 	return &Token{
-		AccessToken:  t.AccessToken,
-		TokenType:    t.TokenType,
-		RefreshToken: t.RefreshToken,
-		IDToken:      t.IDToken,
-		Expiry:       t.Expiry,
-		raw:          t.Raw,
+		AccessToken:     t.AccessToken,
+		TokenType:       t.TokenType,
+		RefreshToken:    t.RefreshToken,
+		IDToken:         t.IDToken,
+		IssuedTokenType: t.IssuedTokenType,
+		Expiry:          t.Expiry,
+		raw:             t.Raw,
 	}
 }
 
@@ -179,7 +192,7 @@ func tokenFromInternal(t *internal.Token) *Token {
 // This token is then mapped from *internal.Token into an *oauth2.Token which is returned along
 // with an error.
 func retrieveToken(ctx context.Context, c *Config, v url.Values) (*Token, error) {
-	tk, err := internal.RetrieveToken(ctx, c.ClientID, c.ClientSecret, c.Endpoint.TokenURL, v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get())
+	tk, err := internal.RetrieveToken(ctx, c.clientAuth(), c.tokenURL(), v, internal.AuthStyle(c.Endpoint.AuthStyle), c.authStyleCache.Get())
 	if err != nil {
 		var rErr *internal.RetrieveError
 
@@ -0,0 +1,114 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// GrantTypeTokenExchange is the RFC 8693 token exchange grant type.
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// The RFC 8693 token type identifiers.
+// https://datatracker.ietf.org/doc/html/rfc8693#section-3
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeSAML1        = "urn:ietf:params:oauth:token-type:saml1"
+	TokenTypeSAML2        = "urn:ietf:params:oauth:token-type:saml2"
+	TokenTypeJWT          = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeRequest describes an RFC 8693 Token Exchange Request.
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.1
+type TokenExchangeRequest struct {
+	// SubjectToken is the token representing the identity of the party on
+	// behalf of whom the request is made. It's required.
+	SubjectToken string
+
+	// SubjectTokenType is the type of the SubjectToken, such as
+	// TokenTypeAccessToken. It's required.
+	SubjectTokenType string
+
+	// ActorToken optionally is the token representing the identity of the
+	// acting party.
+	ActorToken string
+
+	// ActorTokenType is the type of the ActorToken. It's required when the
+	// ActorToken is set.
+	ActorTokenType string
+
+	// RequestedTokenType optionally is the type of the requested token.
+	RequestedTokenType string
+
+	// Resource optionally lists the URIs of the target services.
+	Resource []string
+
+	// Audience optionally lists the logical names of the target services.
+	Audience []string
+
+	// Scopes optionally lists the requested scopes.
+	Scopes []string
+}
+
+func (r *TokenExchangeRequest) values() (url.Values, error) {
+	if r.SubjectToken == "" || r.SubjectTokenType == "" {
+		return nil, errors.New("oauth2: token exchange requires a subject token and subject token type")
+	}
+
+	if r.ActorToken != "" && r.ActorTokenType == "" {
+		return nil, errors.New("oauth2: token exchange requires an actor token type with the actor token")
+	}
+
+	v := url.Values{
+		"grant_type":         {GrantTypeTokenExchange},
+		"subject_token":      {r.SubjectToken},
+		"subject_token_type": {r.SubjectTokenType},
+	}
+
+	if r.ActorToken != "" {
+		v.Set("actor_token", r.ActorToken)
+		v.Set("actor_token_type", r.ActorTokenType)
+	}
+
+	if r.RequestedTokenType != "" {
+		v.Set("requested_token_type", r.RequestedTokenType)
+	}
+
+	if len(r.Resource) > 0 {
+		v["resource"] = append([]string(nil), r.Resource...)
+	}
+
+	if len(r.Audience) > 0 {
+		v["audience"] = append([]string(nil), r.Audience...)
+	}
+
+	if len(r.Scopes) > 0 {
+		v.Set("scope", strings.Join(r.Scopes, " "))
+	}
+
+	return v, nil
+}
+
+// ExchangeToken performs an RFC 8693 token exchange, such as to obtain a token
+// to call another service on behalf of the subject of a token. The client
+// authenticates to the token endpoint in the same manner as Exchange, and the
+// type of the issued token is available as Token.IssuedTokenType.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+//
+// See https://datatracker.ietf.org/doc/html/rfc8693.
+func (c *Config) ExchangeToken(ctx context.Context, req TokenExchangeRequest, opts ...AuthCodeOption) (*Token, error) {
+	v, err := req.values()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+
+	return retrieveToken(ctx, c, v)
+}
@@ -0,0 +1,127 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrTokenNotFound is returned by a TokenStore when there is no token stored
+// with the key.
+var ErrTokenNotFound = errors.New("oauth2: token not found")
+
+// TokenStore durably stores tokens with a key chosen by the caller, such as
+// the identifier of the user or the service the token is for. Implementations
+// must be safe for concurrent use by multiple goroutines.
+//
+// See the tokenstore package for a file based implementation.
+type TokenStore interface {
+	// Load returns the token stored with key, or ErrTokenNotFound.
+	Load(ctx context.Context, key string) (*Token, error)
+
+	// Save stores t with key, replacing any token stored with it.
+	Save(ctx context.Context, key string, t *Token) error
+
+	// Delete removes the token stored with key. It's not an error if there
+	// is no such token.
+	Delete(ctx context.Context, key string) error
+
+	// Lock acquires an exclusive lock of the token stored with key, which is
+	// held across loading, refreshing and saving the token so that the
+	// processes sharing the store don't refresh it concurrently with the same
+	// refresh token. The calls to Load, Save and Delete made with the returned
+	// context are made under the lock until unlock is called.
+	Lock(ctx context.Context, key string) (lctx context.Context, unlock func(), err error)
+}
+
+// StoredTokenSource returns a TokenSource that returns the token stored in
+// store with key until it expires, automatically refreshing it as necessary
+// using the provided context.
+//
+// The token is loaded from the store before each refresh, under the lock of
+// the store, so a token refreshed by another process sharing the store is
+// used rather than refreshed again. Every refreshed token is saved to the
+// store before the lock is released and it's returned, so a refresh token
+// rotated by the authorization server survives a restart of the process. If
+// the refresh token is rejected with the 'invalid_grant' error, the token is
+// deleted from the store unless it was replaced in the meantime.
+//
+// The initial token, such as one returned by Exchange, must be saved to the
+// store by the caller.
+func (c *Config) StoredTokenSource(ctx context.Context, store TokenStore, key string) TokenSource {
+	return &reuseTokenSource{
+		ctx: c.hooksContext(ctx),
+		new: &tokenRefresher{
+			ctx:   ctx,
+			conf:  c,
+			store: store,
+			key:   key,
+		},
+	}
+}
+
+// storedToken loads the token from the store, refreshing and saving it if
+// it's expired, while holding the lock of the store.
+func (tf *tokenRefresher) storedToken() (*Token, error) {
+	ctx, unlock, err := tf.store.Lock(tf.ctx, tf.key)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot lock token: %w", err)
+	}
+	defer unlock()
+
+	stored, err := tf.store.Load(ctx, tf.key)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot load token: %w", err)
+	}
+
+	if stored.Valid() {
+		return stored, nil
+	}
+
+	if stored.RefreshToken == "" {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+
+	tk, err := retrieveToken(tf.ctx, tf.conf, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {stored.RefreshToken},
+	})
+
+	if err != nil {
+		var rErr *RetrieveError
+		if errors.As(err, &rErr) && rErr.ErrorCode == "invalid_grant" {
+			// The refresh token is no longer usable, so neither is the
+			// stored token, unless it was replaced in the meantime.
+			if dErr := tf.deleteStored(ctx, stored.RefreshToken); dErr != nil {
+				return nil, errors.Join(err, fmt.Errorf("oauth2: cannot delete token: %w", dErr))
+			}
+		}
+		return nil, err
+	}
+
+	// The previous refresh token may have been revoked by the rotation, so
+	// the token is only returned once it's durably saved.
+	if err = tf.store.Save(ctx, tf.key, tk); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot save token: %w", err)
+	}
+
+	return tk, nil
+}
+
+// deleteStored deletes the stored token if its refresh token is still
+// refreshToken.
+func (tf *tokenRefresher) deleteStored(ctx context.Context, refreshToken string) error {
+	stored, err := tf.store.Load(ctx, tf.key)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if stored.RefreshToken != refreshToken {
+		return nil
+	}
+
+	return tf.store.Delete(ctx, tf.key)
+}
@@ -0,0 +1,229 @@
+// Package tokenstore implements durable oauth2.TokenStore implementations.
+package tokenstore // import "authelia.com/client/oauth2/tokenstore"
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"authelia.com/client/oauth2"
+)
+
+// FileStore is an oauth2.TokenStore which stores each token in a file of a
+// directory. Tokens are written to a temporary file which is atomically
+// renamed over the previous one, and access to each token is serialized with
+// an advisory lock on a file next to it so the store may be shared by several
+// processes, including while a token is refreshed.
+//
+// Advisory file locks are only available on Unix. On other platforms, access
+// is only serialized within the process and the directory isn't synced after
+// a token is written, so a FileStore mustn't be shared by several processes.
+//
+// When the FileStore has an encryption key, the tokens are encrypted at rest
+// with AES-GCM.
+type FileStore struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewFileStore returns a FileStore which stores tokens in dir, creating it if
+// necessary. If key isn't empty, the tokens are encrypted with it, and it
+// must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewFileStore(dir string, key []byte) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("tokenstore: cannot create directory: %w", err)
+	}
+
+	s := &FileStore{dir: dir}
+
+	if len(key) != 0 {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("tokenstore: invalid encryption key: %w", err)
+		}
+
+		if s.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("tokenstore: invalid encryption key: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// path returns the path of the file of the token stored with key. The key is
+// hashed as it's chosen by the caller and may not be a valid file name.
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, name(key)+".json")
+}
+
+// lockPath returns the path of the lock file of the token stored with key.
+func (s *FileStore) lockPath(key string) string {
+	return filepath.Join(s.dir, name(key)+".lock")
+}
+
+func name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Load implements oauth2.TokenStore.
+func (s *FileStore) Load(ctx context.Context, key string) (*oauth2.Token, error) {
+	unlock, err := s.lock(ctx, key, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, oauth2.ErrTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("tokenstore: cannot read token: %w", err)
+	}
+
+	if s.aead != nil {
+		if len(data) < s.aead.NonceSize() {
+			return nil, errors.New("tokenstore: cannot decrypt token: data is too short")
+		}
+
+		nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+
+		// The key is authenticated so a file can't be swapped with the
+		// token of another key.
+		if data, err = s.aead.Open(nil, nonce, ciphertext, []byte(key)); err != nil {
+			return nil, fmt.Errorf("tokenstore: cannot decrypt token: %w", err)
+		}
+	}
+
+	t := &oauth2.Token{}
+
+	if err = json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("tokenstore: cannot parse token: %w", err)
+	}
+
+	return t, nil
+}
+
+// Save implements oauth2.TokenStore.
+func (s *FileStore) Save(ctx context.Context, key string, t *oauth2.Token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return err
+		}
+
+		data = s.aead.Seal(nonce, nonce, data, []byte(key))
+	}
+
+	unlock, err := s.lock(ctx, key, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.CreateTemp(s.dir, ".token-*")
+	if err != nil {
+		return fmt.Errorf("tokenstore: cannot write token: %w", err)
+	}
+
+	name := f.Name()
+
+	if err = writeSync(f, data); err != nil {
+		os.Remove(name)
+		return fmt.Errorf("tokenstore: cannot write token: %w", err)
+	}
+
+	if err = os.Rename(name, s.path(key)); err != nil {
+		os.Remove(name)
+		return fmt.Errorf("tokenstore: cannot write token: %w", err)
+	}
+
+	return syncDir(s.dir)
+}
+
+// Delete implements oauth2.TokenStore.
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	unlock, err := s.lock(ctx, key, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("tokenstore: cannot delete token: %w", err)
+	}
+
+	return nil
+}
+
+// Lock implements oauth2.TokenStore. It acquires the exclusive lock of the
+// token stored with key, which the calls made with the returned context for
+// the same key don't acquire again.
+func (s *FileStore) Lock(ctx context.Context, key string) (context.Context, func(), error) {
+	unlock, err := s.lock(ctx, key, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return context.WithValue(ctx, heldLockKey{s, key}, true), unlock, nil
+}
+
+// heldLockKey is the context key marking the lock of a token of the FileStore
+// as held by the caller.
+type heldLockKey struct {
+	s   *FileStore
+	key string
+}
+
+// lock acquires the lock of the token stored with key, which is exclusive
+// when writing, and returns the function which releases it. If ctx is from
+// Lock for the same key, the lock is already held and isn't acquired again.
+func (s *FileStore) lock(ctx context.Context, key string, exclusive bool) (unlock func(), err error) {
+	if held, _ := ctx.Value(heldLockKey{s, key}).(bool); held {
+		return func() {}, nil
+	}
+
+	f, err := os.OpenFile(s.lockPath(key), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("tokenstore: cannot open lock: %w", err)
+	}
+
+	if err = lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("tokenstore: cannot acquire lock: %w", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func writeSync(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,133 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2"
+)
+
+func TestFileStore(t *testing.T) {
+	for _, key := range [][]byte{nil, make([]byte, 32)} {
+		dir := t.TempDir()
+
+		s, err := NewFileStore(dir, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.Background()
+
+		if _, err = s.Load(ctx, "user/1"); !errors.Is(err, oauth2.ErrTokenNotFound) {
+			t.Errorf("Load of missing token error = %v; want ErrTokenNotFound", err)
+		}
+
+		want := &oauth2.Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+		if err = s.Save(ctx, "user/1", want); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Load(ctx, "user/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken || !got.Expiry.Equal(want.Expiry) {
+			t.Errorf("Load = %+v; want %+v", got, want)
+		}
+
+		data, err := os.ReadFile(s.path("user/1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted := !strings.Contains(string(data), "REFRESH_TOKEN"); encrypted != (key != nil) {
+			t.Errorf("Stored token encrypted = %v; want %v", encrypted, key != nil)
+		}
+
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".token-") {
+				t.Errorf("Temporary file %s was left behind", e.Name())
+			}
+		}
+
+		if err = s.Delete(ctx, "user/1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Load(ctx, "user/1"); !errors.Is(err, oauth2.ErrTokenNotFound) {
+			t.Errorf("Load of deleted token error = %v; want ErrTokenNotFound", err)
+		}
+		if err = s.Delete(ctx, "user/1"); err != nil {
+			t.Errorf("Delete of missing token = %v; want nil", err)
+		}
+	}
+}
+
+func TestFileStoreLock(t *testing.T) {
+	s, err := NewFileStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The calls made with the context of Lock don't acquire the lock again.
+	ctx, unlock, err := s.Lock(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Save(ctx, "a", &oauth2.Token{AccessToken: "ACCESS_TOKEN"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Load(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tokens of other keys aren't locked.
+	if _, err = s.Load(context.Background(), "b"); !errors.Is(err, oauth2.ErrTokenNotFound) {
+		t.Errorf("Load of other key error = %v; want ErrTokenNotFound", err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		s.Load(context.Background(), "a")
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Error("Load without the context of Lock didn't wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+}
+
+func TestFileStoreWrongKey(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := NewFileStore(dir, make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Save(context.Background(), "a", &oauth2.Token{AccessToken: "ACCESS_TOKEN"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file swapped with the token of another key fails to decrypt.
+	if err = os.Rename(s.path("a"), s.path("b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Load(context.Background(), "b"); err == nil {
+		t.Error("Load of swapped token = nil error; want error")
+	}
+
+	if _, err = NewFileStore(dir, make([]byte, 10)); err == nil {
+		t.Error("NewFileStore with invalid key = nil error; want error")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !unix
+
+package tokenstore
+
+import (
+	"os"
+	"sync"
+)
+
+// locks holds a mutex per lock file which serializes access to the token
+// within the process, as advisory file locks aren't available.
+var locks sync.Map
+
+func lockFile(f *os.File, exclusive bool) error {
+	mu, _ := locks.LoadOrStore(f.Name(), new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	mu, _ := locks.Load(f.Name())
+	mu.(*sync.Mutex).Unlock()
+
+	return nil
+}
+
+// syncDir does nothing, as directories can't be synced on every platform.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,154 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryTokenStore struct {
+	lockMu  sync.Mutex
+	mu      sync.Mutex
+	tokens  map[string]*Token
+	saveErr error
+}
+
+func (s *memoryTokenStore) Load(ctx context.Context, key string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[key]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return t, nil
+}
+
+func (s *memoryTokenStore) Save(ctx context.Context, key string, t *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	s.tokens[key] = t
+	return nil
+}
+
+func (s *memoryTokenStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
+
+func (s *memoryTokenStore) Lock(ctx context.Context, key string) (context.Context, func(), error) {
+	s.lockMu.Lock()
+	return ctx, s.lockMu.Unlock, nil
+}
+
+func TestStoredTokenSource(t *testing.T) {
+	var refreshes int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("refresh_token") {
+		case "REFRESH_TOKEN":
+			refreshes++
+			w.Write([]byte(`{"access_token":"NEW_ACCESS_TOKEN","refresh_token":"ROTATED_REFRESH_TOKEN","token_type":"bearer","expires_in":3600}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	store := &memoryTokenStore{tokens: map[string]*Token{
+		"valid":   {AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(time.Hour)},
+		"expired": {AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(-time.Hour)},
+		"revoked": {AccessToken: "ACCESS_TOKEN", RefreshToken: "REVOKED", Expiry: time.Now().Add(-time.Hour)},
+	}}
+
+	tok, err := conf.StoredTokenSource(context.Background(), store, "valid").Token()
+	if err != nil || tok.AccessToken != "ACCESS_TOKEN" || refreshes != 0 {
+		t.Errorf("Token of valid stored token = %v, %v after %d refreshes; want ACCESS_TOKEN without refresh", tok, err, refreshes)
+	}
+
+	tok, err = conf.StoredTokenSource(context.Background(), store, "expired").Token()
+	if err != nil || tok.AccessToken != "NEW_ACCESS_TOKEN" {
+		t.Fatalf("Token of expired stored token = %v, %v; want NEW_ACCESS_TOKEN", tok, err)
+	}
+	if saved := store.tokens["expired"]; saved.RefreshToken != "ROTATED_REFRESH_TOKEN" {
+		t.Errorf("Saved refresh token = %q; want ROTATED_REFRESH_TOKEN", saved.RefreshToken)
+	}
+
+	var rErr *RetrieveError
+	if _, err = conf.StoredTokenSource(context.Background(), store, "revoked").Token(); !errors.As(err, &rErr) {
+		t.Errorf("Token of revoked stored token error = %v; want RetrieveError", err)
+	}
+	if _, ok := store.tokens["revoked"]; ok {
+		t.Error("Token rejected with invalid_grant wasn't deleted from the store")
+	}
+
+	if _, err = conf.StoredTokenSource(context.Background(), store, "missing").Token(); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Token of missing stored token error = %v; want ErrTokenNotFound", err)
+	}
+
+	store.tokens["unsaved"] = &Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(-time.Hour)}
+	store.saveErr = errors.New("disk full")
+	if _, err = conf.StoredTokenSource(context.Background(), store, "unsaved").Token(); !errors.Is(err, store.saveErr) {
+		t.Errorf("Token with failing store error = %v; want %v", err, store.saveErr)
+	}
+}
+
+func TestStoredTokenSourceReload(t *testing.T) {
+	var (
+		store *memoryTokenStore
+		sent  []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		sent = append(sent, r.PostForm.Get("refresh_token"))
+		switch r.PostForm.Get("refresh_token") {
+		case "REFRESH_TOKEN_1":
+			w.Write([]byte(`{"access_token":"ACCESS_TOKEN_2","refresh_token":"REFRESH_TOKEN_2","token_type":"bearer","expires_in":1}`))
+		default:
+			// Another process rotates the stored token while this refresh
+			// token is rejected.
+			store.tokens["key"] = &Token{AccessToken: "ACCESS_TOKEN_4", RefreshToken: "REFRESH_TOKEN_4", Expiry: time.Now().Add(time.Hour)}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	store = &memoryTokenStore{tokens: map[string]*Token{
+		"key": {AccessToken: "ACCESS_TOKEN_1", RefreshToken: "REFRESH_TOKEN_1", Expiry: time.Now().Add(-time.Hour)},
+	}}
+	src := conf.StoredTokenSource(context.Background(), store, "key")
+
+	if tok, err := src.Token(); err != nil || tok.AccessToken != "ACCESS_TOKEN_2" {
+		t.Fatalf("Token = %v, %v; want ACCESS_TOKEN_2", tok, err)
+	}
+
+	// The token is refreshed with the refresh token stored by another
+	// process rather than the one this source last saw.
+	store.tokens["key"] = &Token{AccessToken: "ACCESS_TOKEN_3", RefreshToken: "REFRESH_TOKEN_3", Expiry: time.Now().Add(-time.Hour)}
+	if _, err := src.Token(); err == nil {
+		t.Fatal("Token with rejected refresh token = nil error; want error")
+	}
+	if want := []string{"REFRESH_TOKEN_1", "REFRESH_TOKEN_3"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("Sent refresh tokens = %q; want %q", sent, want)
+	}
+
+	// The token which replaced the rejected one isn't deleted.
+	if tok := store.tokens["key"]; tok == nil || tok.RefreshToken != "REFRESH_TOKEN_4" {
+		t.Errorf("Stored token = %v; want the REFRESH_TOKEN_4 token", tok)
+	}
+}
@@ -5,10 +5,15 @@
 package oauth2
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"sync"
+
+	"authelia.com/client/oauth2/internal"
 )
 
 // Transport is an http.RoundTripper that makes OAuth 2.0 HTTP requests,
@@ -25,6 +30,36 @@ type Transport struct {
 	// Base is the base RoundTripper used to make HTTP requests.
 	// If nil, http.DefaultTransport is used.
 	Base http.RoundTripper
+
+	// DPoP optionally creates the DPoP proofs for tokens of type DPoP. When
+	// set, such tokens are sent using the DPoP Authorization scheme along with
+	// a proof bound to the request and the token, as described in RFC 9449.
+	DPoP *DPoP
+
+	// RefreshOnInvalidToken optionally retries a request once with a new
+	// token when the resource server rejects the token with a 401 response
+	// and the RFC 6750 'invalid_token' error, such as when it was revoked
+	// before it expired. The token cached by the Source is discarded, which
+	// requires the Source to be one returned by this package such as
+	// ReuseTokenSource or Config.TokenSource. Concurrent requests rejected
+	// with the same token share a single refresh. Requests with a body are
+	// only retried when the request's GetBody is set.
+	RefreshOnInvalidToken bool
+
+	// ChallengeErrors optionally makes RoundTrip return a *ChallengeError
+	// rather than the response when the resource server responds with a 401
+	// or 403 status code and a Bearer or DPoP WWW-Authenticate challenge with
+	// an error, such as an RFC 9470 step-up challenge. The response, with its
+	// body, is available from the error's Response and Body.
+	ChallengeErrors bool
+}
+
+// invalidator is implemented by the TokenSources which cache a token, so
+// that the Transport can discard a token rejected by the resource server.
+type invalidator interface {
+	// invalidate discards the cached token if it's t, so that the next call
+	// to Token obtains a new one.
+	invalidate(t *Token)
 }
 
 // RoundTrip authorizes and authenticates the request with an
@@ -52,7 +87,81 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	// req.Body is assumed to be closed by the base RoundTripper.
 	reqBodyClosed = true
-	return t.base().RoundTrip(req2)
+
+	resp, err := t.roundTrip(req2, token)
+	if err == nil && t.RefreshOnInvalidToken && resp.StatusCode == http.StatusUnauthorized && invalidTokenChallenge(resp.Header) {
+		resp, err = t.retryInvalidToken(req, token, resp)
+	}
+
+	if err != nil || !t.ChallengeErrors {
+		return resp, err
+	}
+
+	return t.challengeError(resp)
+}
+
+// retryInvalidToken retries req with a new token after resp rejected token.
+// If it can't be retried, resp is returned.
+func (t *Transport) retryInvalidToken(req *http.Request, token *Token, resp *http.Response) (*http.Response, error) {
+	inv, ok := t.Source.(invalidator)
+	if !ok || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return resp, nil
+	}
+
+	inv.invalidate(token)
+
+	retryToken, err := t.Source.Token()
+	if err != nil || retryToken.AccessToken == token.AccessToken {
+		return resp, nil
+	}
+
+	req3 := cloneRequest(req)
+	if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+		if req3.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	retryToken.SetAuthHeader(req3)
+
+	// The response is replaced by the one to the retried request.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	return t.roundTrip(req3, retryToken)
+}
+
+// challengeError returns a *ChallengeError if resp has a Bearer or DPoP
+// challenge with an error, and otherwise returns resp.
+func (t *Transport) challengeError(resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+
+	e := ParseChallengeError(resp)
+	if e == nil {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot read response body: %w", err)
+	}
+
+	// The body is replaced so it can still be read from the error's Response.
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	e.Body = body
+
+	return nil, e
+}
+
+func (t *Transport) roundTrip(req *http.Request, token *Token) (*http.Response, error) {
+	if t.DPoP != nil && token.Type() == "DPoP" {
+		dt := &internal.DPoPTransport{Prover: t.DPoP, Base: t.base()}
+		return dt.RoundTrip(req)
+	}
+
+	return t.base().RoundTrip(req)
 }
 
 var cancelOnce sync.Once
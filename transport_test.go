@@ -1,10 +1,14 @@
 package oauth2
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -154,3 +158,111 @@ func TestExpiredWithExpiry(t *testing.T) {
 func newMockServer(handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(handler))
 }
+
+func TestTransportRefreshOnInvalidToken(t *testing.T) {
+	var refreshes atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"NEW_ACCESS_TOKEN","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	resourceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer NEW_ACCESS_TOKEN" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="example", error="invalid_token", error_description="The access token was revoked"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer resourceServer.Close()
+
+	conf := newConf(tokenServer.URL)
+	conf.Endpoint.AuthStyle = AuthStyleInParams
+	tok := &Token{AccessToken: "REVOKED_ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(time.Hour)}
+	client := &http.Client{Transport: &Transport{Source: conf.TokenSource(context.Background(), tok), RefreshOnInvalidToken: true}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Post(resourceServer.URL, "text/plain", strings.NewReader("BODY"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != "BODY" {
+				t.Errorf("Response = %d %q; want 200 BODY", resp.StatusCode, body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("Token was refreshed %d times; want 1", n)
+	}
+}
+
+func TestTransportRefreshOnInvalidTokenDisabled(t *testing.T) {
+	resourceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer resourceServer.Close()
+
+	tok := &Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(time.Hour)}
+	for _, refresh := range []bool{false, true} {
+		// Without a refresh token the same token is obtained, so the request
+		// isn't retried either way.
+		client := &http.Client{Transport: &Transport{Source: ReuseTokenSource(tok, StaticTokenSource(tok)), RefreshOnInvalidToken: refresh}}
+		resp, err := client.Get(resourceServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Response status = %d; want %d", resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestTransportChallengeErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication", error_description="A different authentication level is required", acr_values="myACR", max_age="5"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("step-up"))
+	}))
+	defer ts.Close()
+
+	tok := &Token{AccessToken: "ACCESS_TOKEN"}
+	client := &http.Client{Transport: &Transport{Source: StaticTokenSource(tok), ChallengeErrors: true}}
+
+	resp, err := client.Get(ts.URL)
+	if resp != nil {
+		resp.Body.Close()
+		t.Fatalf("Response = %v; want nil", resp)
+	}
+
+	var cErr *ChallengeError
+	if !errors.As(err, &cErr) {
+		t.Fatalf("Error = %v; want *ChallengeError", err)
+	}
+	if !errors.Is(err, ErrInsufficientUserAuthentication) || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Error = %v; want it to match only ErrInsufficientUserAuthentication", err)
+	}
+	if cErr.ACRValues != "myACR" || !cErr.HasMaxAge || cErr.MaxAge != 5*time.Second {
+		t.Errorf("ACRValues, MaxAge = %q, %v; want %q, %v", cErr.ACRValues, cErr.MaxAge, "myACR", 5*time.Second)
+	}
+	if string(cErr.Body) != "step-up" || cErr.Response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Response = %d %q; want %d %q", cErr.Response.StatusCode, cErr.Body, http.StatusUnauthorized, "step-up")
+	}
+	if body, _ := io.ReadAll(cErr.Response.Body); string(body) != "step-up" {
+		t.Errorf("Response body = %q; want %q", body, "step-up")
+	}
+}
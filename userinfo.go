@@ -0,0 +1,318 @@
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"authelia.com/client/oauth2/internal"
+	"authelia.com/client/oauth2/internal/jwe"
+)
+
+// UserInfo describes an OpenID Connect 1.0 UserInfo Response containing the
+// Standard Claims.
+// https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims
+type UserInfo struct {
+	Subject             string           `json:"sub"`
+	Name                string           `json:"name,omitempty"`
+	GivenName           string           `json:"given_name,omitempty"`
+	FamilyName          string           `json:"family_name,omitempty"`
+	MiddleName          string           `json:"middle_name,omitempty"`
+	Nickname            string           `json:"nickname,omitempty"`
+	PreferredUsername   string           `json:"preferred_username,omitempty"`
+	Profile             string           `json:"profile,omitempty"`
+	Picture             string           `json:"picture,omitempty"`
+	Website             string           `json:"website,omitempty"`
+	Email               string           `json:"email,omitempty"`
+	EmailVerified       bool             `json:"email_verified,omitempty"`
+	Gender              string           `json:"gender,omitempty"`
+	Birthdate           string           `json:"birthdate,omitempty"`
+	Zoneinfo            string           `json:"zoneinfo,omitempty"`
+	Locale              string           `json:"locale,omitempty"`
+	PhoneNumber         string           `json:"phone_number,omitempty"`
+	PhoneNumberVerified bool             `json:"phone_number_verified,omitempty"`
+	Address             *UserInfoAddress `json:"address,omitempty"`
+	UpdatedAt           time.Time        `json:"-"`
+
+	// raw is the JSON object containing every claim of the response.
+	raw []byte
+
+	// claims contains every claim of the response.
+	claims map[string]any
+}
+
+// UserInfoAddress describes the OpenID Connect 1.0 Address Claim.
+// https://openid.net/specs/openid-connect-core-1_0.html#AddressClaim
+type UserInfoAddress struct {
+	Formatted     string `json:"formatted,omitempty"`
+	StreetAddress string `json:"street_address,omitempty"`
+	Locality      string `json:"locality,omitempty"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postal_code,omitempty"`
+	Country       string `json:"country,omitempty"`
+}
+
+func (u *UserInfo) UnmarshalJSON(data []byte) (err error) {
+	type Alias UserInfo
+
+	aux := &struct {
+		UpdatedAt int64 `json:"updated_at"`
+		*Alias
+	}{
+		Alias: (*Alias)(u),
+	}
+
+	if err = json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.UpdatedAt != 0 {
+		u.UpdatedAt = time.Unix(aux.UpdatedAt, 0)
+	}
+
+	u.raw = append([]byte(nil), data...)
+
+	return json.Unmarshal(data, &u.claims)
+}
+
+// Claims unmarshals the raw claims of the response into v, which allows
+// decoding claims not represented by the UserInfo fields.
+func (u *UserInfo) Claims(v any) error {
+	if u.raw == nil {
+		return errors.New("oauth2: userinfo has no claims")
+	}
+
+	return json.Unmarshal(u.raw, v)
+}
+
+// Extra returns a claim of the response, including any which are not
+// represented by the UserInfo fields.
+func (u *UserInfo) Extra(key string) any {
+	return u.claims[key]
+}
+
+// UserInfo retrieves the claims about the end-user authorized by token from
+// the Endpoint.UserinfoURL.
+//
+// Both application/json and application/jwt responses are supported. Signed
+// responses are verified with the keys of the Endpoint.JWKSURL, and encrypted
+// responses are decrypted with the DecryptionKey. When the token contains an
+// ID Token, the 'sub' claim of the response must match that of the ID Token.
+//
+// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+func (c *Config) UserInfo(ctx context.Context, token *Token) (*UserInfo, error) {
+	if token == nil || token.AccessToken == "" {
+		return nil, errors.New("error retrieving userinfo: no token was provided")
+	}
+
+	if c.userinfoURL() == "" {
+		return nil, errors.New("error retrieving userinfo: no userinfo endpoint URL was provided")
+	}
+
+	cctx := internal.ContextWithCertificate(ctx, c.ClientCertificate)
+
+	req, err := http.NewRequest("GET", c.userinfoURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json, application/jwt")
+
+	r, err := internal.Observe(c.hooksContext(cctx), EndpointKindUserInfo, "", func(cctx context.Context) (*http.Response, error) {
+		return newClient(cctx, StaticTokenSource(token), c.DPoP).Do(req.WithContext(cctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot fetch userinfo: %v", err)
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return nil, newUserInfoError(r, body)
+	}
+
+	content, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if content == "application/jwt" {
+		if body, err = c.userinfoClaims(ctx, string(bytes.TrimSpace(body))); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &UserInfo{}
+
+	if err = json.Unmarshal(body, info); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse userinfo response: %w", err)
+	}
+
+	if info.Subject == "" {
+		return nil, errors.New("oauth2: userinfo response has no 'sub' claim")
+	}
+
+	if token.IDToken != "" {
+		sub, err := c.idTokenSubject(token.IDToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if sub != info.Subject {
+			return nil, fmt.Errorf("oauth2: userinfo 'sub' claim %q does not match the ID Token 'sub' claim %q", info.Subject, sub)
+		}
+	}
+
+	return info, nil
+}
+
+// userinfoClaims returns the claims of a signed, encrypted, or signed and then
+// encrypted UserInfo response.
+func (c *Config) userinfoClaims(ctx context.Context, token string) ([]byte, error) {
+	if jwe.IsEncrypted(token) {
+		plaintext, err := c.decrypt(token)
+		if err != nil {
+			return nil, fmt.Errorf("oauth2: cannot decrypt userinfo response: %w", err)
+		}
+
+		// The response may be encrypted without being signed.
+		if trimmed := bytes.TrimSpace(plaintext); len(trimmed) != 0 && trimmed[0] == '{' {
+			return trimmed, nil
+		}
+
+		token = string(plaintext)
+	}
+
+	ks, err := c.KeySet()
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot verify userinfo response: %w", err)
+	}
+
+	payload, err := ks.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot verify userinfo response: %w", err)
+	}
+
+	var claims struct {
+		Issuer   string          `json:"iss"`
+		Audience json.RawMessage `json:"aud"`
+	}
+
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse userinfo response: %w", err)
+	}
+
+	if claims.Issuer != "" && c.Endpoint.Issuer != "" && claims.Issuer != c.Endpoint.Issuer {
+		return nil, fmt.Errorf("oauth2: userinfo 'iss' claim %q does not match the issuer %q", claims.Issuer, c.Endpoint.Issuer)
+	}
+
+	if len(claims.Audience) != 0 && !audienceContains(claims.Audience, c.ClientID) {
+		return nil, fmt.Errorf("oauth2: userinfo 'aud' claim does not contain the client id %q", c.ClientID)
+	}
+
+	return payload, nil
+}
+
+// decrypt decrypts the JWE token with the DecryptionKey.
+func (c *Config) decrypt(token string) ([]byte, error) {
+	if c.DecryptionKey == nil {
+		return nil, errors.New("no decryption key was provided")
+	}
+
+	plaintext, _, err := jwe.Decrypt(token, c.DecryptionKey)
+
+	return plaintext, err
+}
+
+// idTokenSubject returns the 'sub' claim of the ID Token. The ID Token isn't
+// verified as it's expected to have been verified when it was received.
+func (c *Config) idTokenSubject(idToken string) (string, error) {
+	if jwe.IsEncrypted(idToken) {
+		plaintext, err := c.decrypt(idToken)
+		if err != nil {
+			return "", fmt.Errorf("oauth2: cannot decrypt ID Token: %w", err)
+		}
+
+		idToken = string(plaintext)
+	}
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("oauth2: cannot parse ID Token: token must have 3 parts")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("oauth2: cannot parse ID Token: %w", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("oauth2: cannot parse ID Token: %w", err)
+	}
+
+	return claims.Subject, nil
+}
+
+// audienceContains returns true if the 'aud' claim, which may be a single
+// string or an array of strings, contains value.
+func audienceContains(aud json.RawMessage, value string) bool {
+	var single string
+	if err := json.Unmarshal(aud, &single); err == nil {
+		return single == value
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(aud, &multiple); err != nil {
+		return false
+	}
+
+	for _, v := range multiple {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newUserInfoError(r *http.Response, body []byte) *UserInfoError {
+	e := &UserInfoError{
+		BaseError: &BaseError{
+			Response: r,
+			Body:     body,
+		},
+	}
+
+	var ej struct {
+		ErrorCode        string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorURI         string `json:"error_uri"`
+	}
+
+	if err := json.Unmarshal(body, &ej); err == nil {
+		e.ErrorCode = ej.ErrorCode
+		e.ErrorDescription = ej.ErrorDescription
+		e.ErrorURI = ej.ErrorURI
+	}
+
+	return e
+}
+
+// UserInfoError is the error returned when the userinfo endpoint returns a
+// non-2XX HTTP status code.
+type UserInfoError struct {
+	*BaseError
+}
@@ -0,0 +1,154 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2/internal/jwe"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func TestUserInfo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "/userinfo" {
+			t.Errorf("Unexpected userinfo URL %q", r.URL)
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer ACCESS_TOKEN"; got != want {
+			t.Errorf("Authorization header = %q; want %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"sub":"abc","name":"John Smith","email":"john@example.com","email_verified":true,"address":{"country":"AU"},"updated_at":1700000000,"groups":["admins"]}`)
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.Endpoint.UserinfoURL = ts.URL + "/userinfo"
+	info, err := conf.UserInfo(context.Background(), &Token{AccessToken: "ACCESS_TOKEN", IDToken: unsignedIDToken(t, "abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "abc" || info.Name != "John Smith" || info.Email != "john@example.com" || !info.EmailVerified {
+		t.Errorf("Unexpected userinfo %+v", info)
+	}
+	if info.Address == nil || info.Address.Country != "AU" {
+		t.Errorf("Unexpected address %+v", info.Address)
+	}
+	if !info.UpdatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("UpdatedAt = %v; want %v", info.UpdatedAt, time.Unix(1700000000, 0))
+	}
+	var claims struct {
+		Groups []string `json:"groups"`
+	}
+	if err = info.Claims(&claims); err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Groups) != 1 || claims.Groups[0] != "admins" {
+		t.Errorf("Unexpected groups %v", claims.Groups)
+	}
+
+	_, err = conf.UserInfo(context.Background(), &Token{AccessToken: "ACCESS_TOKEN", IDToken: unsignedIDToken(t, "xyz")})
+	if err == nil {
+		t.Error("UserInfo with mismatched ID Token sub = nil error; want error")
+	}
+}
+
+func TestUserInfoSignedAndEncrypted(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jwks":
+			jwk, _ := jws.PublicJWK(signingKey.Public())
+			jwk["kid"] = "key1"
+			json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+		case "/userinfo":
+			signer, alg, _ := jws.NewSigner("", signingKey)
+			claims := map[string]any{"iss": ts.URL, "aud": "CLIENT_ID", "sub": "abc", "name": "John Smith"}
+			token, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg, "kid": "key1", "typ": "JWT"}, claims, signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.URL.Query().Get("encrypted") == "true" {
+				token, err = jwe.Encrypt([]byte(token), &jwe.Header{Algorithm: "RSA-OAEP-256", Encryption: "A256GCM", ContentType: "JWT"}, &encryptionKey.PublicKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			w.Header().Set("Content-Type", "application/jwt")
+			io.WriteString(w, token)
+		default:
+			t.Errorf("Unexpected URL %q", r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	conf := newConf(ts.URL)
+	conf.DecryptionKey = encryptionKey
+	conf.Endpoint.Issuer = ts.URL
+	conf.Endpoint.JWKSURL = ts.URL + "/jwks"
+
+	for _, uri := range []string{"/userinfo", "/userinfo?encrypted=true"} {
+		conf.Endpoint.UserinfoURL = ts.URL + uri
+		info, err := conf.UserInfo(context.Background(), &Token{AccessToken: "ACCESS_TOKEN"})
+		if err != nil {
+			t.Fatalf("%s: %v", uri, err)
+		}
+		if info.Subject != "abc" || info.Name != "John Smith" {
+			t.Errorf("%s: unexpected userinfo %+v", uri, info)
+		}
+		if got := info.Extra("iss"); got != ts.URL {
+			t.Errorf("%s: Extra(iss) = %v; want %s", uri, got, ts.URL)
+		}
+	}
+
+	conf.Endpoint.Issuer = "https://other.example.com"
+	conf.Endpoint.UserinfoURL = ts.URL + "/userinfo"
+	if _, err = conf.UserInfo(context.Background(), &Token{AccessToken: "ACCESS_TOKEN"}); err == nil {
+		t.Error("UserInfo with mismatched issuer = nil error; want error")
+	}
+}
+
+func TestUserInfoError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error": "invalid_token", "error_description": "expired"}`)
+	}))
+	defer ts.Close()
+	conf := newConf(ts.URL)
+	conf.Endpoint.UserinfoURL = ts.URL + "/userinfo"
+	_, err := conf.UserInfo(context.Background(), &Token{AccessToken: "ACCESS_TOKEN"})
+	var uErr *UserInfoError
+	if !errors.As(err, &uErr) {
+		t.Fatalf("got %T error, expected *UserInfoError; error was: %v", err, err)
+	}
+	if uErr.ErrorCode != "invalid_token" || uErr.ErrorDescription != "expired" {
+		t.Errorf("Unexpected error %+v", uErr.BaseError)
+	}
+}
+
+func unsignedIDToken(t *testing.T, sub string) string {
+	token, err := jws.EncodeClaimsWithSigner(map[string]any{"alg": "none"}, map[string]any{"sub": sub}, func([]byte) ([]byte, error) { return nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
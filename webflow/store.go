@@ -0,0 +1,275 @@
+package webflow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCookieName is the prefix of the flow state cookie names when
+// CookieOptions.Name is empty.
+const DefaultCookieName = "oauth2_flow"
+
+// ErrFlowStateNotFound is returned by a FlowStateStore when there is no
+// unexpired flow state for the state of an authorization response which is
+// bound to the user agent.
+var ErrFlowStateNotFound = errors.New("webflow: flow state was not found")
+
+// FlowState is the state of a single authorization attempt, which is kept
+// between the Login and Callback requests.
+type FlowState struct {
+	// State is the 'state' parameter of the authorization request.
+	State string `json:"state"`
+
+	// Nonce is the 'nonce' parameter of the authorization request. It's
+	// compared to the 'nonce' claim of the ID Token.
+	Nonce string `json:"nonce"`
+
+	// Verifier is the PKCE code verifier of the authorization request.
+	Verifier string `json:"verifier"`
+
+	// Expires is when the authorization attempt expires.
+	Expires time.Time `json:"expires"`
+}
+
+// FlowStateStore stores the FlowState of authorization attempts. The flow
+// state must be bound to the user agent which started the attempt, so an
+// authorization response can't be injected into another user agent's session.
+type FlowStateStore interface {
+	// Save stores the flow state fs for the user agent of r.
+	Save(w http.ResponseWriter, r *http.Request, fs *FlowState) error
+
+	// Load removes and returns the unexpired flow state of the user agent of
+	// r with the given state, or returns ErrFlowStateNotFound.
+	Load(w http.ResponseWriter, r *http.Request, state string) (*FlowState, error)
+}
+
+// CookieOptions describes the cookies used by the flow state stores to bind
+// the flow state to the user agent. A cookie is set for each authorization
+// attempt, named with the Name and the state.
+type CookieOptions struct {
+	// Name optionally specifies the prefix of the cookie names. If empty,
+	// DefaultCookieName is used.
+	Name string
+
+	// Path optionally specifies the cookie path. If empty, "/" is used.
+	Path string
+
+	// Domain optionally specifies the cookie domain.
+	Domain string
+
+	// Insecure omits the Secure attribute, such as when developing without
+	// TLS.
+	Insecure bool
+
+	// SameSite optionally specifies the SameSite attribute. If zero,
+	// http.SameSiteLaxMode is used. The form_post response modes are cross
+	// site POST requests, which require http.SameSiteNoneMode.
+	SameSite http.SameSite
+}
+
+func (o *CookieOptions) name(state string) string {
+	name := o.Name
+	if name == "" {
+		name = DefaultCookieName
+	}
+
+	return name + "_" + state
+}
+
+func (o *CookieOptions) cookie(state, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     o.name(state),
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		Expires:  expires,
+		Secure:   !o.Insecure,
+		HttpOnly: true,
+		SameSite: o.SameSite,
+	}
+
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	return cookie
+}
+
+// set sets the cookie of the state on w.
+func (o *CookieOptions) set(w http.ResponseWriter, state, value string, expires time.Time) {
+	http.SetCookie(w, o.cookie(state, value, expires))
+}
+
+// take returns the value of the cookie of the state from r, and clears it on
+// w.
+func (o *CookieOptions) take(w http.ResponseWriter, r *http.Request, state string) (string, bool) {
+	cookie, err := r.Cookie(o.name(state))
+	if err != nil {
+		return "", false
+	}
+
+	expired := o.cookie(state, "", time.Time{})
+	expired.MaxAge = -1
+
+	http.SetCookie(w, expired)
+
+	return cookie.Value, true
+}
+
+// CookieStore is a FlowStateStore which keeps the flow state in a cookie
+// encrypted with AES-GCM, so no server side storage is required.
+type CookieStore struct {
+	CookieOptions
+
+	aead cipher.AEAD
+}
+
+// NewCookieStore returns a CookieStore which encrypts the flow state with
+// key, which must be 16, 24 or 32 bytes long to select AES-128, AES-192 or
+// AES-256. The key must be kept secret and shared by every instance of the
+// application.
+func NewCookieStore(key []byte, opts CookieOptions) (*CookieStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("webflow: invalid cookie store key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("webflow: invalid cookie store key: %w", err)
+	}
+
+	return &CookieStore{CookieOptions: opts, aead: aead}, nil
+}
+
+// Save implements FlowStateStore.
+func (s *CookieStore) Save(w http.ResponseWriter, r *http.Request, fs *FlowState) error {
+	plaintext, err := json.Marshal(fs)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	// The cookie name is authenticated so the value can't be moved to the
+	// cookie of another state.
+	ciphertext := s.aead.Seal(nonce, nonce, plaintext, []byte(s.name(fs.State)))
+
+	s.set(w, fs.State, base64.RawURLEncoding.EncodeToString(ciphertext), fs.Expires)
+
+	return nil
+}
+
+// Load implements FlowStateStore.
+func (s *CookieStore) Load(w http.ResponseWriter, r *http.Request, state string) (*FlowState, error) {
+	value, ok := s.take(w, r, state)
+	if !ok {
+		return nil, ErrFlowStateNotFound
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(ciphertext) < s.aead.NonceSize() {
+		return nil, ErrFlowStateNotFound
+	}
+
+	nonce, ciphertext := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(s.name(state)))
+	if err != nil {
+		return nil, ErrFlowStateNotFound
+	}
+
+	fs := &FlowState{}
+
+	if err = json.Unmarshal(plaintext, fs); err != nil {
+		return nil, err
+	}
+
+	if fs.State != state || !timeNow().Before(fs.Expires) {
+		return nil, ErrFlowStateNotFound
+	}
+
+	return fs, nil
+}
+
+// MemoryStore is a FlowStateStore which keeps the flow state in memory, with
+// only a random handle in the user agent's cookie. It's only suitable when a
+// single instance of the application handles both the Login and Callback.
+type MemoryStore struct {
+	CookieOptions
+
+	mu     sync.Mutex // guards states
+	states map[string]*FlowState
+}
+
+// NewMemoryStore returns a MemoryStore.
+func NewMemoryStore(opts CookieOptions) *MemoryStore {
+	return &MemoryStore{
+		CookieOptions: opts,
+		states:        map[string]*FlowState{},
+	}
+}
+
+// Save implements FlowStateStore.
+func (s *MemoryStore) Save(w http.ResponseWriter, r *http.Request, fs *FlowState) error {
+	handle, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timeNow()
+
+	for key, state := range s.states {
+		if !now.Before(state.Expires) {
+			delete(s.states, key)
+		}
+	}
+
+	s.states[handle] = fs
+
+	s.set(w, fs.State, handle, fs.Expires)
+
+	return nil
+}
+
+// Load implements FlowStateStore.
+func (s *MemoryStore) Load(w http.ResponseWriter, r *http.Request, state string) (*FlowState, error) {
+	handle, ok := s.take(w, r, state)
+	if !ok {
+		return nil, ErrFlowStateNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fs, ok := s.states[handle]
+	if !ok {
+		return nil, ErrFlowStateNotFound
+	}
+
+	delete(s.states, handle)
+
+	if fs.State != state || !timeNow().Before(fs.Expires) {
+		return nil, ErrFlowStateNotFound
+	}
+
+	return fs, nil
+}
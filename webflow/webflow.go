@@ -0,0 +1,259 @@
+// Package webflow implements the login and callback http.Handlers of the
+// OAuth 2.0 authorization code flow for web applications.
+//
+// The Login handler starts an authorization attempt with a fresh state, nonce
+// and PKCE code verifier, which are kept in a FlowStateStore, and redirects
+// the user agent to the authorization server. The Callback handler, served at
+// the RedirectURL, validates the authorization response against the stored
+// flow state, exchanges the code for a token, verifies its ID Token if any and
+// passes it to the Handler's OnSuccess function.
+package webflow // import "authelia.com/client/oauth2/webflow"
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/idtoken"
+)
+
+// DefaultExpires is the lifetime of an authorization attempt when
+// Handler.Expires is zero.
+const DefaultExpires = 10 * time.Minute
+
+var errNoOnSuccess = errors.New("webflow: Handler's OnSuccess is nil")
+
+// timeNow is time.Now but pulled out as a variable for tests.
+var timeNow = time.Now
+
+// Handler serves the login and callback requests of the authorization code
+// flow.
+type Handler struct {
+	// Config is the client configuration. Its RedirectURL must be served by
+	// the Callback handler. When its Scopes include "openid" and there's no
+	// IDTokenVerifier, its Endpoint must have an Issuer and a JWKSURL to
+	// verify the ID Token, such as the Endpoint of the oauth2.ProviderMetadata
+	// returned by oauth2.Discover.
+	Config *oauth2.Config
+
+	// Store keeps the flow state between the Login and Callback requests.
+	Store FlowStateStore
+
+	// UsePushedAuth sends the authorization request parameters with
+	// Config.PushedAuth instead of in the authorization URL.
+	UsePushedAuth bool
+
+	// Options optionally specifies additional parameters of the
+	// authorization request.
+	Options []oauth2.AuthCodeOption
+
+	// Expires optionally specifies how long an authorization attempt is
+	// valid for. If zero, DefaultExpires is used.
+	Expires time.Duration
+
+	// IDTokenVerifier optionally verifies the ID Token of the token obtained
+	// by the Callback handler. If nil, idtoken.NewVerifier(Config) is used,
+	// and Login fails without redirecting when it can't verify ID Tokens.
+	IDTokenVerifier *idtoken.Verifier
+
+	// OnSuccess is called by the Callback handler with the token and the flow
+	// state of a successful authorization, and writes the response such as a
+	// redirect after establishing the application's session. When the token
+	// has an ID Token, it has been verified and its 'nonce' claim matches the
+	// flow state's Nonce. OnSuccess is required.
+	OnSuccess func(w http.ResponseWriter, r *http.Request, token *oauth2.Token, fs *FlowState)
+
+	// OnError is optionally called when a request fails, and writes the
+	// response. Authorization responses with RFC 6749's 'error' parameter
+	// are an *oauth2.AuthorizationError. If nil, a plain status response is
+	// written.
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Login starts an authorization attempt and redirects the user agent to the
+// authorization server.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.OnSuccess == nil {
+		h.error(w, r, errNoOnSuccess, http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := h.login(w, r)
+	if err != nil {
+		h.error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) (string, error) {
+	// The ID Token can only be verified once the code has been exchanged, so
+	// a missing issuer or key set is reported before the user agent is sent
+	// to the authorization server.
+	if h.IDTokenVerifier == nil && slices.Contains(h.Config.Scopes, "openid") &&
+		(h.Config.Endpoint.Issuer == "" || h.Config.Endpoint.JWKSURL == "") {
+		return "", errors.New("webflow: Config's Endpoint has no Issuer or JWKSURL to verify ID Tokens")
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	pkce, err := oauth2.NewPKCE()
+	if err != nil {
+		return "", err
+	}
+
+	expires := h.Expires
+	if expires == 0 {
+		expires = DefaultExpires
+	}
+
+	fs := &FlowState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: string(pkce.Verifier()),
+		Expires:  timeNow().Add(expires),
+	}
+
+	opts := append([]oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("nonce", nonce),
+		pkce.AuthCodeOptionChallenge(),
+	}, h.Options...)
+
+	var authURL *url.URL
+
+	if h.UsePushedAuth {
+		authURL, _, err = h.Config.PushedAuth(r.Context(), state, opts...)
+	} else {
+		authURL, err = h.Config.ParsedAuthCodeURL(state, opts...)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = h.Store.Save(w, r, fs); err != nil {
+		return "", fmt.Errorf("webflow: cannot save flow state: %w", err)
+	}
+
+	return authURL.String(), nil
+}
+
+// Callback handles the authorization response delivered to the RedirectURL,
+// and calls OnSuccess with the token obtained by exchanging the code. The ID
+// Token of the token, if any, is verified with the IDTokenVerifier and must
+// have the 'nonce' claim of the authorization request.
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	if h.OnSuccess == nil {
+		h.error(w, r, errNoOnSuccess, http.StatusInternalServerError)
+		return
+	}
+
+	// The flow state bound to the user agent is loaded with the response's
+	// state, and the response is then validated against it.
+	state, err := h.responseState(r)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	fs, err := h.Store.Load(w, r, state)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	ar, err := h.Config.ParseAuthorizationResponse(r, fs.State)
+	if err != nil {
+		h.error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.Config.Exchange(r.Context(), ar.Code, oauth2.VerifierOption(fs.Verifier))
+	if err != nil {
+		h.error(w, r, err, http.StatusBadGateway)
+		return
+	}
+
+	if token.IDToken != "" {
+		if _, err = h.idTokenVerifier().VerifyToken(r.Context(), token, idtoken.NonceOption(fs.Nonce), idtoken.CodeOption(ar.Code)); err != nil {
+			h.error(w, r, fmt.Errorf("webflow: invalid ID Token: %w", err), http.StatusBadGateway)
+			return
+		}
+	}
+
+	h.OnSuccess(w, r, token, fs)
+}
+
+func (h *Handler) idTokenVerifier() *idtoken.Verifier {
+	if h.IDTokenVerifier != nil {
+		return h.IDTokenVerifier
+	}
+
+	return idtoken.NewVerifier(h.Config)
+}
+
+// responseState returns the unvalidated 'state' parameter of the authorization
+// response r, which is within the JWT of JARM responses.
+func (h *Handler) responseState(r *http.Request) (string, error) {
+	v := r.URL.Query()
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			return "", fmt.Errorf("webflow: cannot parse authorization response: %w", err)
+		}
+
+		v = r.PostForm
+	}
+
+	response := v.Get("response")
+	if response == "" {
+		return v.Get("state"), nil
+	}
+
+	ar, err := h.Config.ParseJARM(r.Context(), response)
+
+	var aerr *oauth2.AuthorizationError
+
+	switch {
+	case errors.As(err, &aerr):
+		return aerr.State, nil
+	case err != nil:
+		return "", err
+	}
+
+	return ar.State, nil
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, err error, code int) {
+	if h.OnError != nil {
+		h.OnError(w, r, err)
+		return
+	}
+
+	http.Error(w, http.StatusText(code), code)
+}
+
+// randomString returns a random URL and cookie name safe string.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
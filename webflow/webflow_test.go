@@ -0,0 +1,264 @@
+package webflow
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"authelia.com/client/oauth2"
+	"authelia.com/client/oauth2/idtoken"
+	"authelia.com/client/oauth2/internal/jws"
+)
+
+func newHandler(t *testing.T, store FlowStateStore) (*Handler, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "CODE" || r.Form.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"ACCESS_TOKEN","token_type":"bearer","expires_in":3600}`))
+	}))
+
+	h := &Handler{
+		Config: &oauth2.Config{
+			ClientID:    "CLIENT_ID",
+			RedirectURL: "https://app.example.com/callback",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://as.example.com/auth",
+				TokenURL:  ts.URL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		Store: store,
+		OnSuccess: func(w http.ResponseWriter, r *http.Request, token *oauth2.Token, fs *FlowState) {
+			w.Write([]byte(token.AccessToken))
+		},
+	}
+
+	return h, ts.Close
+}
+
+// login runs the Login handler and returns the authorization request
+// parameters and the cookies set.
+func login(t *testing.T, h *Handler) (url.Values, []*http.Cookie) {
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest("GET", "/login", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Login status = %d; want %d", rec.Code, http.StatusFound)
+	}
+
+	u, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.Query(), rec.Result().Cookies()
+}
+
+func callback(h *Handler, query string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/callback?"+query, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Callback(rec, r)
+
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	cookieStore, err := NewCookieStore(make([]byte, 32), CookieOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]FlowStateStore{
+		"cookie": cookieStore,
+		"memory": NewMemoryStore(CookieOptions{}),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			h, closer := newHandler(t, store)
+			defer closer()
+
+			v, cookies := login(t, h)
+			if v.Get("state") == "" || v.Get("nonce") == "" || v.Get("code_challenge") == "" || v.Get("code_challenge_method") != "S256" {
+				t.Errorf("Unexpected authorization request %v", v)
+			}
+			if len(cookies) != 1 || !cookies[0].Secure || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+				t.Fatalf("Unexpected cookies %v", cookies)
+			}
+
+			if rec := callback(h, "code=CODE&state=OTHER", cookies); rec.Code != http.StatusBadRequest {
+				t.Errorf("Callback with other state status = %d; want %d", rec.Code, http.StatusBadRequest)
+			}
+
+			if rec := callback(h, "code=CODE&state="+v.Get("state"), nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("Callback without cookie status = %d; want %d", rec.Code, http.StatusBadRequest)
+			}
+
+			rec := callback(h, "code=CODE&state="+v.Get("state"), cookies)
+			if rec.Code != http.StatusOK || rec.Body.String() != "ACCESS_TOKEN" {
+				t.Errorf("Callback = %d %q; want 200 ACCESS_TOKEN", rec.Code, rec.Body.String())
+			}
+			if c := rec.Result().Cookies(); len(c) != 1 || c[0].MaxAge != -1 {
+				t.Errorf("Callback cookies = %v; want the flow state cookie cleared", c)
+			}
+
+			if _, err := store.Load(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), v.Get("state")); !errors.Is(err, ErrFlowStateNotFound) {
+				t.Errorf("Load after callback error = %v; want ErrFlowStateNotFound", err)
+			}
+		})
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	h, closer := newHandler(t, NewMemoryStore(CookieOptions{}))
+	defer closer()
+
+	var got error
+	h.OnError = func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+	}
+
+	v, cookies := login(t, h)
+	callback(h, "error=access_denied&state="+v.Get("state"), cookies)
+
+	var aErr *oauth2.AuthorizationError
+	if !errors.As(got, &aErr) || aErr.ErrorCode != "access_denied" {
+		t.Errorf("OnError error = %v; want AuthorizationError access_denied", got)
+	}
+
+	v, cookies = login(t, h)
+	callback(h, "code=OTHER&state="+v.Get("state"), cookies)
+
+	var rErr *oauth2.RetrieveError
+	if !errors.As(got, &rErr) {
+		t.Errorf("OnError error = %v; want RetrieveError", got)
+	}
+}
+
+func TestHandlerIDToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nonce string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/jwks" {
+			jwk, _ := jws.PublicJWK(key.Public())
+			json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+			return
+		}
+
+		signer, alg, _ := jws.NewSigner("", key)
+		idToken, _ := jws.EncodeClaimsWithSigner(map[string]any{"alg": alg, "typ": "JWT"}, map[string]any{
+			"iss":   "https://as.example.com",
+			"sub":   "abc",
+			"aud":   "CLIENT_ID",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": nonce,
+		}, signer)
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "ACCESS_TOKEN", "token_type": "bearer", "id_token": idToken})
+	}))
+	defer ts.Close()
+
+	h, closer := newHandler(t, NewMemoryStore(CookieOptions{}))
+	defer closer()
+	h.Config.Endpoint.Issuer = "https://as.example.com"
+	h.Config.Endpoint.TokenURL = ts.URL
+	h.Config.Endpoint.JWKSURL = ts.URL + "/jwks"
+
+	var got error
+	h.OnError = func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+	}
+
+	v, cookies := login(t, h)
+	nonce = v.Get("nonce")
+	if rec := callback(h, "code=CODE&state="+v.Get("state"), cookies); rec.Code != http.StatusOK || got != nil {
+		t.Errorf("Callback = %d, %v; want 200", rec.Code, got)
+	}
+
+	v, cookies = login(t, h)
+	nonce = "OTHER"
+	if callback(h, "code=CODE&state="+v.Get("state"), cookies); got == nil {
+		t.Error("Callback with other nonce error = nil; want error")
+	}
+}
+
+func TestHandlerNoIDTokenVerifier(t *testing.T) {
+	h, closer := newHandler(t, NewMemoryStore(CookieOptions{}))
+	defer closer()
+	h.Config.Scopes = []string{"openid"}
+
+	// The Endpoint has no Issuer or JWKSURL, so ID Tokens can't be verified.
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest("GET", "/login", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Login without Issuer and JWKSURL status = %d; want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	h.IDTokenVerifier = &idtoken.Verifier{}
+	if v, _ := login(t, h); v.Get("state") == "" {
+		t.Error("Login with IDTokenVerifier has no state")
+	}
+}
+
+func TestHandlerNoOnSuccess(t *testing.T) {
+	h, closer := newHandler(t, NewMemoryStore(CookieOptions{}))
+	defer closer()
+	h.OnSuccess = nil
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest("GET", "/login", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Login without OnSuccess status = %d; want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if rec = callback(h, "code=CODE&state=STATE", nil); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Callback without OnSuccess status = %d; want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestCookieStoreExpiry(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+
+	store, err := NewCookieStore(make([]byte, 16), CookieOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	fs := &FlowState{State: "STATE", Expires: time.Now().Add(time.Minute)}
+	if err = store.Save(rec, httptest.NewRequest("GET", "/", nil), fs); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(rec.Result().Cookies()[0])
+
+	timeNow = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err = store.Load(httptest.NewRecorder(), r, "STATE"); !errors.Is(err, ErrFlowStateNotFound) {
+		t.Errorf("Load of expired flow state error = %v; want ErrFlowStateNotFound", err)
+	}
+
+	if _, err = NewCookieStore(make([]byte, 10), CookieOptions{}); err == nil {
+		t.Error("NewCookieStore with invalid key = nil error; want error")
+	}
+}
@@ -0,0 +1,326 @@
+package oauth2
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken matches a *ChallengeError with the RFC 6750
+	// 'invalid_token' error code, returned when the access token is expired,
+	// revoked or otherwise invalid.
+	ErrInvalidToken = errors.New("oauth2: invalid_token")
+
+	// ErrInsufficientScope matches a *ChallengeError with the RFC 6750
+	// 'insufficient_scope' error code, returned when the access token lacks
+	// the scope in ChallengeError.Scope.
+	ErrInsufficientScope = errors.New("oauth2: insufficient_scope")
+
+	// ErrInsufficientUserAuthentication matches a *ChallengeError with the
+	// RFC 9470 'insufficient_user_authentication' error code, returned when
+	// the user must authenticate again as described by ChallengeError.ACRValues
+	// and ChallengeError.MaxAge.
+	ErrInsufficientUserAuthentication = errors.New("oauth2: insufficient_user_authentication")
+)
+
+// ChallengeError is the error described by a Bearer or DPoP WWW-Authenticate
+// challenge of a protected resource's response, as described in RFC 6750,
+// RFC 9449 and RFC 9470. Use errors.Is with ErrInvalidToken,
+// ErrInsufficientScope or ErrInsufficientUserAuthentication to check the
+// error code.
+//
+// See https://datatracker.ietf.org/doc/html/rfc6750#section-3 and
+// https://datatracker.ietf.org/doc/html/rfc9470#section-3.
+type ChallengeError struct {
+	*BaseError
+
+	// Scheme is the authentication scheme of the challenge, either Bearer or
+	// DPoP.
+	Scheme string
+
+	// Realm is the optional 'realm' parameter of the challenge.
+	Realm string
+
+	// Scope is the optional 'scope' parameter of the challenge, which is the
+	// space delimited scope required to access the resource.
+	Scope string
+
+	// ACRValues is the RFC 9470 'acr_values' parameter of the challenge, which
+	// is the space delimited authentication context class references the user
+	// must authenticate with.
+	ACRValues string
+
+	// MaxAge is the RFC 9470 'max_age' parameter of the challenge, which is
+	// the maximum time since the user last authenticated. It's only set when
+	// HasMaxAge is true, as a MaxAge of zero requires the user to
+	// authenticate again.
+	MaxAge time.Duration
+
+	// HasMaxAge reports whether the challenge has a 'max_age' parameter.
+	HasMaxAge bool
+
+	// Algorithms is the RFC 9449 'algs' parameter of a DPoP challenge, which
+	// lists the JWS algorithms accepted for DPoP proofs.
+	Algorithms []string
+}
+
+// Is reports whether target is the sentinel error of the error code of e.
+func (e *ChallengeError) Is(target error) bool {
+	switch target {
+	case ErrInvalidToken:
+		return e.ErrorCode == "invalid_token"
+	case ErrInsufficientScope:
+		return e.ErrorCode == "insufficient_scope"
+	case ErrInsufficientUserAuthentication:
+		return e.ErrorCode == "insufficient_user_authentication"
+	default:
+		return false
+	}
+}
+
+// AuthCodeOptions returns the AuthCodeOptions which ask the authorization
+// server for a token satisfying the challenge, to be passed to
+// Config.AuthCodeURL so the user can be prompted again rather than failing.
+// The 'acr_values' and 'max_age' parameters are set from the RFC 9470 step-up
+// challenge, and the Scope is added to the requested scope.
+func (e *ChallengeError) AuthCodeOptions() []AuthCodeOption {
+	var opts []AuthCodeOption
+
+	if e.Scope != "" {
+		opts = append(opts, addScopeOption(e.Scope))
+	}
+
+	if e.ACRValues != "" {
+		opts = append(opts, SetAuthURLParam("acr_values", e.ACRValues))
+	}
+
+	if e.HasMaxAge {
+		opts = append(opts, SetAuthURLParam("max_age", strconv.FormatInt(int64(e.MaxAge/time.Second), 10)))
+	}
+
+	return opts
+}
+
+// addScopeOption adds the space delimited scopes to the 'scope' parameter,
+// keeping the scopes already requested.
+type addScopeOption string
+
+func (o addScopeOption) setValue(m url.Values) {
+	scopes := strings.Fields(m.Get("scope"))
+
+	for _, scope := range strings.Fields(string(o)) {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	m.Set("scope", strings.Join(scopes, " "))
+}
+
+// ParseChallengeError returns the error described by the first Bearer or DPoP
+// WWW-Authenticate challenge of resp with an 'error' parameter, or nil if
+// there's none, such as when the request had no access token. The body of
+// resp isn't read.
+func ParseChallengeError(resp *http.Response) *ChallengeError {
+	for _, c := range parseChallenges(resp.Header) {
+		if (!strings.EqualFold(c.scheme, "Bearer") && !strings.EqualFold(c.scheme, "DPoP")) || c.params["error"] == "" {
+			continue
+		}
+
+		e := &ChallengeError{
+			BaseError: &BaseError{
+				Response:         resp,
+				ErrorCode:        c.params["error"],
+				ErrorDescription: c.params["error_description"],
+				ErrorURI:         c.params["error_uri"],
+			},
+			Scheme:    c.scheme,
+			Realm:     c.params["realm"],
+			Scope:     c.params["scope"],
+			ACRValues: c.params["acr_values"],
+		}
+
+		if algs := c.params["algs"]; algs != "" {
+			e.Algorithms = strings.Fields(algs)
+		}
+
+		if maxAge, err := strconv.ParseInt(c.params["max_age"], 10, 64); err == nil && maxAge >= 0 {
+			e.MaxAge, e.HasMaxAge = time.Duration(maxAge)*time.Second, true
+		}
+
+		return e
+	}
+
+	return nil
+}
+
+// challenge is an authentication challenge of a WWW-Authenticate header.
+// https://datatracker.ietf.org/doc/html/rfc9110#section-11.6.1
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseChallenges parses the challenges of the WWW-Authenticate headers h.
+// The auth-param names are lower cased, and a token68 is ignored.
+func parseChallenges(h http.Header) []challenge {
+	var challenges []challenge
+
+	for _, value := range h.Values("WWW-Authenticate") {
+		p := &challengeParser{s: value}
+
+		for {
+			p.skip(" \t,")
+
+			scheme := p.token()
+			if scheme == "" {
+				break
+			}
+
+			c := challenge{scheme: scheme, params: map[string]string{}}
+
+			for {
+				p.skip(" \t")
+
+				start := p.i
+
+				name := p.token()
+				if name == "" {
+					// A token68, which may end with '=' padding.
+					p.skipToken68()
+					p.skip(" \t,")
+					break
+				}
+
+				p.skip(" \t")
+
+				if !p.consume('=') {
+					// The name is the scheme of the next challenge.
+					p.i = start
+					break
+				}
+
+				p.skip(" \t")
+
+				var value string
+				if p.peek() == '"' {
+					value = p.quoted()
+				} else if value = p.token(); value == "" {
+					// A token68 with '=' padding rather than an auth-param.
+					p.skipToken68()
+					p.skip(" \t,")
+					break
+				}
+
+				c.params[strings.ToLower(name)] = value
+
+				p.skip(" \t")
+
+				if !p.consume(',') {
+					break
+				}
+			}
+
+			challenges = append(challenges, c)
+		}
+	}
+
+	return challenges
+}
+
+// invalidTokenChallenge reports whether h has a Bearer or DPoP challenge with
+// the RFC 6750 'invalid_token' error code.
+func invalidTokenChallenge(h http.Header) bool {
+	for _, c := range parseChallenges(h) {
+		if (strings.EqualFold(c.scheme, "Bearer") || strings.EqualFold(c.scheme, "DPoP")) && c.params["error"] == "invalid_token" {
+			return true
+		}
+	}
+
+	return false
+}
+
+type challengeParser struct {
+	s string
+	i int
+}
+
+func (p *challengeParser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+
+	return 0
+}
+
+func (p *challengeParser) consume(c byte) bool {
+	if p.peek() == c && p.i < len(p.s) {
+		p.i++
+		return true
+	}
+
+	return false
+}
+
+func (p *challengeParser) skip(chars string) {
+	for p.i < len(p.s) && strings.IndexByte(chars, p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// token reads an RFC 9110 token.
+func (p *challengeParser) token() string {
+	start := p.i
+
+	for p.i < len(p.s) && isTokenChar(p.s[p.i]) {
+		p.i++
+	}
+
+	return p.s[start:p.i]
+}
+
+func (p *challengeParser) skipToken68() {
+	for p.i < len(p.s) && (isTokenChar(p.s[p.i]) || p.s[p.i] == '/' || p.s[p.i] == '=') {
+		p.i++
+	}
+}
+
+// quoted reads an RFC 9110 quoted-string.
+func (p *challengeParser) quoted() string {
+	var b strings.Builder
+
+	p.i++ // the opening quote
+
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+
+		switch c {
+		case '"':
+			return b.String()
+		case '\\':
+			if p.i < len(p.s) {
+				b.WriteByte(p.s[p.i])
+				p.i++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,158 @@
+package oauth2
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChallenges(t *testing.T) {
+	h := http.Header{}
+	h.Add("WWW-Authenticate", `Negotiate abc==, Basic realm="basic", Bearer realm="ex\"ample", error="invalid_token", scope="a b"`)
+	h.Add("WWW-Authenticate", `DPoP algs="ES256 PS256", error=use_dpop_nonce`)
+
+	got := parseChallenges(h)
+	want := []challenge{
+		{"Negotiate", map[string]string{}},
+		{"Basic", map[string]string{"realm": "basic"}},
+		{"Bearer", map[string]string{"realm": `ex"ample`, "error": "invalid_token", "scope": "a b"}},
+		{"DPoP", map[string]string{"algs": "ES256 PS256", "error": "use_dpop_nonce"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChallenges = %v; want %v", got, want)
+	}
+	if !invalidTokenChallenge(h) {
+		t.Error("invalidTokenChallenge = false; want true")
+	}
+}
+
+func TestParseChallengeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   *ChallengeError
+		is     error
+	}{
+		{
+			name:   "NoError",
+			header: []string{`Bearer realm="example"`},
+		},
+		{
+			name:   "OtherScheme",
+			header: []string{`Basic error="invalid_token"`},
+		},
+		{
+			name:   "InvalidToken",
+			header: []string{`Basic realm="basic"`, `Bearer realm="example", error="invalid_token", error_description="The access token expired"`},
+			want: &ChallengeError{
+				BaseError: &BaseError{ErrorCode: "invalid_token", ErrorDescription: "The access token expired"},
+				Scheme:    "Bearer",
+				Realm:     "example",
+			},
+			is: ErrInvalidToken,
+		},
+		{
+			name:   "InsufficientScope",
+			header: []string{`DPoP algs="ES256 PS256", error="insufficient_scope", scope="read write", error_uri="https://example.com/error"`},
+			want: &ChallengeError{
+				BaseError:  &BaseError{ErrorCode: "insufficient_scope", ErrorURI: "https://example.com/error"},
+				Scheme:     "DPoP",
+				Scope:      "read write",
+				Algorithms: []string{"ES256", "PS256"},
+			},
+			is: ErrInsufficientScope,
+		},
+		{
+			name:   "InsufficientUserAuthentication",
+			header: []string{`Bearer error="insufficient_user_authentication", acr_values="phr phrh", max_age=0`},
+			want: &ChallengeError{
+				BaseError: &BaseError{ErrorCode: "insufficient_user_authentication"},
+				Scheme:    "Bearer",
+				ACRValues: "phr phrh",
+				MaxAge:    0,
+				HasMaxAge: true,
+			},
+			is: ErrInsufficientUserAuthentication,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{"Www-Authenticate": tt.header}}
+
+			got := ParseChallengeError(resp)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseChallengeError = %+v; want nil", got)
+				}
+				return
+			}
+
+			if got == nil {
+				t.Fatal("ParseChallengeError = nil; want an error")
+			}
+			if got.Response != resp {
+				t.Errorf("Response = %v; want %v", got.Response, resp)
+			}
+
+			tt.want.Response = resp
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChallengeError = %+v; want %+v", got, tt.want)
+			}
+			if !errors.Is(got, tt.is) {
+				t.Errorf("errors.Is(%v, %v) = false; want true", got, tt.is)
+			}
+		})
+	}
+}
+
+func TestChallengeErrorAuthCodeOptions(t *testing.T) {
+	e := &ChallengeError{
+		BaseError: &BaseError{ErrorCode: "insufficient_user_authentication"},
+		Scope:     "write openid",
+		ACRValues: "phrh",
+		MaxAge:    90 * time.Second,
+		HasMaxAge: true,
+	}
+
+	conf := &Config{
+		ClientID:    "CLIENT_ID",
+		RedirectURL: "REDIRECT_URL",
+		Scopes:      []string{"openid", "read"},
+		Endpoint:    Endpoint{AuthURL: "https://example.com/auth"},
+	}
+
+	u, err := url.Parse(conf.AuthCodeURL("state", e.AuthCodeOptions()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := u.Query()
+	if got, want := q.Get("scope"), "openid read write"; got != want {
+		t.Errorf("scope = %q; want %q", got, want)
+	}
+	if got, want := q.Get("acr_values"), "phrh"; got != want {
+		t.Errorf("acr_values = %q; want %q", got, want)
+	}
+	if got, want := q.Get("max_age"), "90"; got != want {
+		t.Errorf("max_age = %q; want %q", got, want)
+	}
+
+	// The zero value has no 'max_age' parameter.
+	e = &ChallengeError{BaseError: &BaseError{ErrorCode: "insufficient_user_authentication"}}
+	if opts := e.AuthCodeOptions(); len(opts) != 0 {
+		t.Errorf("AuthCodeOptions = %v; want none", opts)
+	}
+
+	e.HasMaxAge = true
+	u, err = url.Parse(conf.AuthCodeURL("state", e.AuthCodeOptions()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Query().Get("max_age"), "0"; got != want {
+		t.Errorf("max_age = %q; want %q", got, want)
+	}
+}